@@ -1,10 +1,9 @@
 package yae
 
 import (
+	"errors"
 	"fmt"
 	"strings"
-
-	"github.com/zalando/go-keyring"
 )
 
 /*
@@ -19,8 +18,6 @@ key: the name of the secret you are storing/getting
 value: the value of the secret you are storing/getting
 */
 
-var interactive bool = true
-
 type Secret struct {
 	Name  string
 	Value string
@@ -30,18 +27,18 @@ type Secrets []Secret
 
 const svc = "authAsaurusRex"
 
-func notFound(errString string) bool {
-	// keyring will return a specific message if the secret is not found.
+func notFound(err error) bool {
+	// the store will return ErrSecretNotFound if the secret is not found.
 	// if that is returned we know to add the secret. otherwise something
 	// occurred that we need to handle differently.
-	return errString == "secret not found in keyring"
+	return errors.Is(err, ErrSecretNotFound)
 }
 
-func checkKey(service, key string) (string, bool) {
+func checkKey(store SecretStore, service, key string) (string, bool) {
 	// get password
-	secret, err := keyring.Get(key, service)
+	secret, err := store.Get(service, key)
 	if err != nil {
-		if notFound(err.Error()) {
+		if notFound(err) {
 			return "not-found", false
 		}
 		return "", false
@@ -50,7 +47,9 @@ func checkKey(service, key string) (string, bool) {
 	return secret, true
 }
 
-func setKey(service, key string) error {
+// setKey prompts for the value of key and saves it to the store. When interactive
+// is false the prompt is answered with the key name.
+func setKey(store SecretStore, service, key string, interactive bool) error {
 	value, err := SensitiveInputPrompt(
 		&Prompter{
 			Prompt:      BuildPrompt(key),
@@ -66,24 +65,27 @@ func setKey(service, key string) error {
 		return err
 	}
 
-	err = keyring.Set(key, service, value)
+	err = store.Set(service, key, value)
 	if err != nil {
-		fmt.Printf("error setting secret for %s in keyring.\n", key)
+		fmt.Printf("error setting secret for %s in store.\n", key)
 		return err
 	}
 
 	return nil
 }
 
-func getKey(service, key string) (string, bool) {
-	secret, ok := checkKey(service, key)
+func getKey(store SecretStore, service, key string, interactive bool) (string, bool) {
+	secret, ok := checkKey(store, service, key)
 	if !ok {
 		if secret == "not-found" {
-			err := setKey(service, key)
+			if readOnly(store) {
+				return "", false
+			}
+			err := setKey(store, service, key, interactive)
 			if err != nil {
 				return "", false
 			}
-			secret, _ = checkKey(service, key)
+			secret, _ = checkKey(store, service, key)
 		}
 	}
 	return secret, true
@@ -111,19 +113,24 @@ func contains(s []string, e string) bool {
 	return false
 }
 
-func setInteractive(is bool) {
-	interactive = is
+// GetConfig will return a slice of key, values from the keyring based on the args passed.
+func GetConfig(service string, args ...string) *Secrets {
+	return GetStoreConfig(KeyringStore{}, service, args...)
 }
 
-// GetConfig will return a slice of key, values based on the args passed.
-func GetConfig(service string, args ...string) *Secrets {
+// GetStoreConfig will return a slice of key, values from the store based on the args passed.
+func GetStoreConfig(store SecretStore, service string, args ...string) *Secrets {
+	return getStoreConfig(store, service, true, args...)
+}
+
+func getStoreConfig(store SecretStore, service string, interactive bool, args ...string) *Secrets {
 	secrets := Secrets{}
 	if service == "" {
 		service = svc
 	}
 
 	for _, a := range args {
-		secret, ok := getKey(service, a)
+		secret, ok := getKey(store, service, a, interactive)
 		if ok {
 			secrets = append(secrets, Secret{Name: a, Value: secret})
 		}
@@ -151,7 +151,7 @@ func TestCheckKeyNotFound(t *testing.T) {
 	service := "testService"
 	key := strings.Join(randomStringArray, ",")
 
-	secret, found := checkKey(service, key)
+	secret, found := checkKey(KeyringStore{}, service, key)
 
 	if found {
 		t.Errorf("Expected key not to be found, but it was found. Secret: %s", secret)
@@ -169,7 +169,7 @@ func TestCheckKeyFound(t *testing.T) {
 		t.Fatalf("Failed to set key. Error: %s", err.Error())
 	}
 
-	secret, found := checkKey(service, key)
+	secret, found := checkKey(KeyringStore{}, service, key)
 
 	if !found {
 		t.Error("Expected key to be found, but it was not found.")
@@ -195,8 +195,7 @@ func TestGetKeyNotFound(t *testing.T) {
 
 	service := "testService"
 	key := strings.Join(randomStringArray, ",")
-	setInteractive(false)
-	secret, found := getKey(service, key)
+	secret, found := getKey(KeyringStore{}, service, key, false)
 
 	if !found {
 		t.Errorf("Expected key to be set when not found. Secret: %s", secret)
@@ -214,7 +213,7 @@ func TestGetKeyFound(t *testing.T) {
 		t.Fatalf("Failed to set key. Error: %s", err.Error())
 	}
 
-	secret, found := getKey(service, key)
+	secret, found := getKey(KeyringStore{}, service, key, false)
 
 	if !found {
 		t.Error("Expected key to be found, but it was not found.")
@@ -256,7 +255,7 @@ func TestCheckKey(t *testing.T) {
 		t.Fatal(err)
 	}
 	time.Sleep(1 * time.Second)
-	secret, ok := checkKey("key1", testSvc)
+	secret, ok := checkKey(KeyringStore{}, "key1", testSvc)
 	if !ok {
 		t.Errorf("expected ok=true, got ok=false")
 	}
@@ -265,7 +264,7 @@ func TestCheckKey(t *testing.T) {
 	}
 
 	// Test when secret is not found
-	secret, ok = checkKey("nonexistent", testSvc)
+	secret, ok = checkKey(KeyringStore{}, "nonexistent", testSvc)
 	if ok {
 		t.Errorf("expected ok=false, got ok=true")
 	}
@@ -280,10 +279,9 @@ func TestGetConfig(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	setInteractive(false)
 
 	// Test case 1: Get existing secrets
-	secrets := GetConfig(testSvc, "key1", "key2")
+	secrets := getStoreConfig(KeyringStore{}, testSvc, false, "key1", "key2")
 	if len(*secrets) != 2 {
 		t.Errorf("Expected 2 secrets, got %d", len(*secrets))
 	}
@@ -311,7 +309,7 @@ func TestGetConfig(t *testing.T) {
 	}
 
 	// Test case 3: Empty service name
-	secrets = GetConfig("", "key1", "key2")
+	secrets = getStoreConfig(KeyringStore{}, "", false, "key1", "key2")
 	if len(*secrets) != 2 {
 		t.Errorf("Expected 2 secrets, got %d", len(*secrets))
 	}
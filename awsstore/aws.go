@@ -0,0 +1,235 @@
+/*
+Package awsstore provides yae secret stores backed by AWS Secrets Manager and
+Systems Manager Parameter Store.
+
+The stores live in their own package so that only programs using them depend
+on the AWS SDK:
+
+	var conf Config
+	err := yae.New(
+		yae.WithFile("myapp.json", yae.JSON),
+		yae.WithSecretStore(&awsstore.SecretsManagerStore{Config: awsstore.Config{Region: "eu-west-1"}}),
+	).Load(yae.PROD, &conf)
+*/
+package awsstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+/*
+Config holds the region, endpoint and credentials shared by the AWS secret
+stores. Requests are signed with Signature Version 4.
+
+Unless set, the endpoint is read from AWS_ENDPOINT_URL_<SERVICE> or
+AWS_ENDPOINT_URL, and the region and credentials are resolved like the AWS
+SDK does with config.LoadDefaultConfig: from the AWS_* variables, the shared
+config and credentials files with AWS_PROFILE, web identity tokens (EKS
+IRSA), SSO, the ECS or EKS Pod Identity container endpoint or the EC2
+instance metadata service. Those credentials are cached until they expire.
+The Credentials provider takes precedence over that chain, and AccessKeyID
+over both.
+*/
+type Config struct {
+	Region          string       // AWS region, e.g. eu-west-1
+	Endpoint        string       // Endpoint URL, e.g. http://localhost:4566 for a local stand-in
+	AccessKeyID     string       // Static access key ID
+	SecretAccessKey string       // Static secret access key
+	SessionToken    string       // Session token of temporary static credentials
+	Client          *http.Client // HTTP client, defaults to http.DefaultClient
+
+	// Credentials provides credentials when no static ones are set. It is
+	// called for every request, so wrap it in aws.NewCredentialsCache unless
+	// it caches them itself.
+	Credentials aws.CredentialsProvider
+}
+
+// service is an AWS API the stores call.
+type service struct {
+	name string // signing name and endpoint prefix
+	env  string // suffix of the AWS_ENDPOINT_URL_ variable
+}
+
+var (
+	secretsManager = service{name: "secretsmanager", env: "SECRETS_MANAGER"}
+	ssm            = service{name: "ssm", env: "SSM"}
+)
+
+// credentialCache holds the config a store loaded with the SDK's default
+// chain, so its credentials are cached across requests.
+type credentialCache struct {
+	mu  sync.Mutex
+	cfg *aws.Config
+}
+
+// apiError is an error returned by an AWS JSON API.
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("aws returned status %d: %s", e.status, e.code)
+	}
+	return fmt.Sprintf("aws returned status %d: %s: %s", e.status, e.code, e.message)
+}
+
+// errorCode returns the code of an AWS error, or an empty string.
+func errorCode(err error) string {
+	var aerr *apiError
+	if errors.As(err, &aerr) {
+		return aerr.code
+	}
+	return ""
+}
+
+// call sends a JSON 1.1 request for target, e.g. secretsmanager.GetSecretValue,
+// to svc and decodes the response into out.
+func (a *Config) call(ctx context.Context, cache *credentialCache, svc service, target string, in, out interface{}) error {
+	region, err := a.region(ctx, cache)
+	if err != nil {
+		return err
+	}
+
+	endpoint := a.Endpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL_" + svc.env)
+	}
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", svc.name, region)
+	}
+
+	creds, err := a.credentials(ctx, cache)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", target, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", target, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	bodyHash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(bodyHash[:]), svc.name, region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign %s request: %w", target, err)
+	}
+
+	resp, err := a.client().Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		aerr := &apiError{status: resp.StatusCode}
+		var payload struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+			Upper   string `json:"Message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&payload) == nil {
+			// the type may be namespaced, e.g. com.amazonaws.secretsmanager#ResourceNotFoundException
+			aerr.code = payload.Type[strings.LastIndex(payload.Type, "#")+1:]
+			aerr.message = payload.Message + payload.Upper
+		}
+		return aerr
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", target, err)
+	}
+	return nil
+}
+
+func (a *Config) client() *http.Client {
+	if a.Client != nil {
+		return a.Client
+	}
+	return http.DefaultClient
+}
+
+// region returns Region, or the region of the SDK's default config.
+func (a *Config) region(ctx context.Context, cache *credentialCache) (string, error) {
+	if a.Region != "" {
+		return a.Region, nil
+	}
+	cfg, err := cache.load(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Region == "" {
+		return "", errors.New("aws region is not set, set Region, AWS_REGION or a region in the shared config")
+	}
+	return cfg.Region, nil
+}
+
+// credentials returns the static credentials, those of the Credentials
+// provider or those of the SDK's default chain.
+func (a *Config) credentials(ctx context.Context, cache *credentialCache) (aws.Credentials, error) {
+	if a.AccessKeyID != "" {
+		return aws.Credentials{AccessKeyID: a.AccessKeyID, SecretAccessKey: a.SecretAccessKey, SessionToken: a.SessionToken}, nil
+	}
+
+	provider := a.Credentials
+	if provider == nil {
+		cfg, err := cache.load(ctx)
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+		provider = cfg.Credentials
+	}
+	if provider == nil {
+		return aws.Credentials{}, errors.New("no aws credentials found")
+	}
+
+	creds, err := provider.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("failed to get aws credentials: %w", err)
+	}
+	return creds, nil
+}
+
+// load returns the SDK's default config, loading it on first use.
+func (c *credentialCache) load(ctx context.Context) (aws.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cfg != nil {
+		return *c.cfg, nil
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	c.cfg = &cfg
+	return cfg, nil
+}
@@ -0,0 +1,378 @@
+package awsstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/johnmikee/yae"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAWS is a minimal stand-in for the Secrets Manager and SSM JSON APIs.
+type fakeAWS struct {
+	mu       sync.Mutex
+	secrets  map[string]string
+	params   map[string]string
+	requests map[string]int
+	delay    time.Duration
+}
+
+func newFakeAWS(t *testing.T) (*fakeAWS, Config) {
+	f := &fakeAWS{secrets: map[string]string{}, params: map[string]string{}, requests: map[string]int{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, Config{Region: "eu-west-1", Endpoint: srv.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"}
+}
+
+func (f *fakeAWS) fail(w http.ResponseWriter, code string) {
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"__type": code, "message": code})
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.Header.Get("X-Amz-Target")
+	service := "ssm"
+	if strings.HasPrefix(target, "secretsmanager.") {
+		service = "secretsmanager"
+	}
+	if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") ||
+		!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/"+service+"/aws4_request") {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"__type": "AccessDeniedException"})
+		return
+	}
+
+	var in map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&in)
+	name, _ := in["Name"].(string)
+	id, _ := in["SecretId"].(string)
+
+	if target == "secretsmanager.GetSecretValue" {
+		time.Sleep(f.delay)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests[target]++
+
+	switch target {
+	case "secretsmanager.GetSecretValue":
+		value, ok := f.secrets[id]
+		if !ok {
+			f.fail(w, "ResourceNotFoundException")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"Name": id, "SecretString": value})
+	case "secretsmanager.CreateSecret":
+		f.secrets[name] = in["SecretString"].(string)
+		w.Write([]byte("{}"))
+	case "secretsmanager.PutSecretValue":
+		f.secrets[id] = in["SecretString"].(string)
+		w.Write([]byte("{}"))
+	case "secretsmanager.DeleteSecret":
+		if _, ok := f.secrets[id]; !ok {
+			f.fail(w, "ResourceNotFoundException")
+			return
+		}
+		delete(f.secrets, id)
+		w.Write([]byte("{}"))
+	case "AmazonSSM.GetParameter":
+		value, ok := f.params[name]
+		if !ok {
+			f.fail(w, "com.amazonaws.ssm#ParameterNotFound")
+			return
+		}
+		if in["WithDecryption"] != true {
+			value = "ENCRYPTED"
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"Parameter": map[string]string{"Name": name, "Value": value}})
+	case "AmazonSSM.PutParameter":
+		if in["Type"] != "SecureString" {
+			f.fail(w, "ValidationException")
+			return
+		}
+		f.params[name] = in["Value"].(string)
+		w.Write([]byte("{}"))
+	case "AmazonSSM.DeleteParameter":
+		if _, ok := f.params[name]; !ok {
+			f.fail(w, "ParameterNotFound")
+			return
+		}
+		delete(f.params, name)
+		w.Write([]byte("{}"))
+	case "AmazonSSM.GetParametersByPath":
+		// one parameter per page to exercise NextToken
+		path, _ := in["Path"].(string)
+		token, _ := in["NextToken"].(string)
+		var names []string
+		for n := range f.params {
+			if strings.HasPrefix(n, path+"/") && n > token {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		resp := map[string]interface{}{"Parameters": []map[string]string{}}
+		if len(names) > 0 {
+			resp["Parameters"] = []map[string]string{{"Name": names[0]}}
+			if len(names) > 1 {
+				resp["NextToken"] = names[0]
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	default:
+		f.fail(w, "UnknownOperationException")
+	}
+}
+
+func TestSecretsManagerStore(t *testing.T) {
+	fake, cfg := newFakeAWS(t)
+	fake.secrets["tls-cert"] = "-----BEGIN CERTIFICATE-----"
+	store := &SecretsManagerStore{Config: cfg}
+
+	_, err := store.Get("myapp", "api_key")
+	assert.ErrorIs(t, err, yae.ErrSecretNotFound)
+
+	assert.NoError(t, store.Set("myapp", "api_key", "abc123"))
+	assert.NoError(t, store.Set("myapp", "region", "eu-west-1"))
+	assert.NoError(t, store.Set("other", "prod/database#password", "hunter2"))
+	assert.JSONEq(t, `{"api_key":"abc123","region":"eu-west-1"}`, fake.secrets["myapp"])
+
+	value, err := store.Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+
+	value, err = store.Get("myapp", "prod/database#password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	value, err = store.Get("myapp", "tls-cert#")
+	assert.NoError(t, err)
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----", value)
+	_, err = store.Get("myapp", "tls-cert#pem")
+	assert.ErrorContains(t, err, "is not a JSON object")
+
+	keys, err := store.List("myapp")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "region"}, keys)
+
+	assert.NoError(t, store.Delete("myapp", "region"))
+	assert.ErrorIs(t, store.Delete("myapp", "region"), yae.ErrSecretNotFound)
+	assert.NoError(t, store.Delete("myapp", "tls-cert#"))
+	assert.ErrorIs(t, store.Delete("myapp", "tls-cert#"), yae.ErrSecretNotFound)
+
+	_, err = (&SecretsManagerStore{Config: Config{Region: "eu-west-1", Endpoint: cfg.Endpoint, AccessKeyID: "bad"}}).Get("myapp", "api_key")
+	assert.ErrorContains(t, err, "AccessDeniedException")
+}
+
+func TestSSMStore(t *testing.T) {
+	fake, cfg := newFakeAWS(t)
+	fake.params["/shared/database/password"] = "hunter2"
+	store := &SSMStore{Config: cfg, Prefix: "prod"}
+
+	_, err := store.Get("myapp", "api_key")
+	assert.ErrorIs(t, err, yae.ErrSecretNotFound)
+
+	assert.NoError(t, store.Set("myapp", "api_key", "abc123"))
+	assert.NoError(t, store.Set("myapp", "database.host", "db.internal"))
+	assert.Equal(t, "abc123", fake.params["/prod/myapp/api_key"])
+
+	value, err := store.Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+
+	value, err = store.Get("myapp", "/shared/database/password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	keys, err := store.List("myapp")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "database.host"}, keys)
+
+	assert.NoError(t, store.Delete("myapp", "api_key"))
+	assert.ErrorIs(t, store.Delete("myapp", "api_key"), yae.ErrSecretNotFound)
+}
+
+func TestSecretsManagerStoreLoad(t *testing.T) {
+	fake, cfg := newFakeAWS(t)
+	fake.secrets["myapp"] = `{"api_key":"abc123","port":8080,"token":"t0k3n"}`
+	fake.delay = 50 * time.Millisecond
+
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Port   int    `json:"port"`
+		Token  string `json:"token"`
+	}
+
+	var conf Conf
+	err := yae.New(
+		yae.WithFile("myapp.json", yae.JSON),
+		yae.WithKeyringService("myapp"),
+		yae.WithSecretStore(&SecretsManagerStore{Config: cfg}),
+	).Load(yae.PROD, &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, Conf{APIKey: "abc123", Port: 8080, Token: "t0k3n"}, conf)
+	assert.Equal(t, 1, fake.requests["secretsmanager.GetSecretValue"])
+}
+
+// isolateAWS clears the AWS settings of the environment, so only those a test
+// sets are seen by the SDK's default chain.
+func isolateAWS(t *testing.T) string {
+	dir := t.TempDir()
+	for _, k := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_REGION", "AWS_DEFAULT_REGION", "AWS_ROLE_ARN", "AWS_WEB_IDENTITY_TOKEN_FILE",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN", "AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_STS",
+	} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	return dir
+}
+
+func TestCredentials(t *testing.T) {
+	isolateAWS(t)
+
+	var cfg Config
+	_, err := cfg.credentials(context.Background(), &credentialCache{})
+	assert.Error(t, err)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+	creds, err := cfg.credentials(context.Background(), &credentialCache{})
+	assert.NoError(t, err)
+	assert.Equal(t, "AKID", creds.AccessKeyID)
+	assert.Equal(t, "env-secret", creds.SecretAccessKey)
+
+	// a provider takes precedence over the default chain, static keys over both
+	provided := Config{Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "provided", SecretAccessKey: "provided-secret"}, nil
+	})}
+	creds, err = provided.credentials(context.Background(), &credentialCache{})
+	assert.NoError(t, err)
+	assert.Equal(t, "provided", creds.AccessKeyID)
+
+	provided.AccessKeyID, provided.SecretAccessKey = "static", "static-secret"
+	creds, err = provided.credentials(context.Background(), &credentialCache{})
+	assert.NoError(t, err)
+	assert.Equal(t, "static", creds.AccessKeyID)
+}
+
+func TestCredentialsProfile(t *testing.T) {
+	dir := isolateAWS(t)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "credentials"),
+		[]byte("[default]\naws_access_key_id = DEFAULT\naws_secret_access_key = default-secret\n\n"+
+			"[dev]\naws_access_key_id = DEV\naws_secret_access_key = dev-secret\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config"), []byte("[profile dev]\nregion = eu-central-1\n"), 0o600))
+	t.Setenv("AWS_PROFILE", "dev")
+
+	var (
+		cfg   Config
+		cache credentialCache
+	)
+	creds, err := cfg.credentials(context.Background(), &cache)
+	assert.NoError(t, err)
+	assert.Equal(t, "DEV", creds.AccessKeyID)
+	assert.Equal(t, "dev-secret", creds.SecretAccessKey)
+
+	region, err := cfg.region(context.Background(), &cache)
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-central-1", region)
+
+	t.Setenv("AWS_REGION", "us-east-2")
+	region, err = cfg.region(context.Background(), &credentialCache{})
+	assert.NoError(t, err)
+	assert.Equal(t, "us-east-2", region)
+}
+
+func TestCredentialsWebIdentity(t *testing.T) {
+	dir := isolateAWS(t)
+	token := filepath.Join(dir, "token")
+	assert.NoError(t, os.WriteFile(token, []byte("pod-token"), 0o600))
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRoleWithWebIdentity" || r.Form.Get("WebIdentityToken") != "pod-token" ||
+			r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/myapp" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIA</AccessKeyId>
+      <SecretAccessKey>role-secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	// the environment EKS sets up for IRSA, with STS pointed at the stand-in
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/myapp")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", token)
+	t.Setenv("AWS_ENDPOINT_URL_STS", srv.URL)
+
+	var (
+		cfg   Config
+		cache credentialCache
+	)
+	creds, err := cfg.credentials(context.Background(), &cache)
+	assert.NoError(t, err)
+	assert.Equal(t, "ASIA", creds.AccessKeyID)
+	assert.Equal(t, "role-secret", creds.SecretAccessKey)
+	assert.Equal(t, "session", creds.SessionToken)
+	assert.True(t, creds.CanExpire)
+
+	_, err = cfg.credentials(context.Background(), &cache)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls, "credentials are cached until they expire")
+}
+
+func TestCredentialsContainer(t *testing.T) {
+	isolateAWS(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "pod-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"AccessKeyId":     "ASIA",
+			"SecretAccessKey": "secret",
+			"Token":           "session",
+			"Expiration":      time.Now().Add(time.Hour),
+		})
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", srv.URL+"/v1/credentials")
+
+	var cfg Config
+	_, err := cfg.credentials(context.Background(), &credentialCache{})
+	assert.Error(t, err)
+
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "pod-token")
+	creds, err := cfg.credentials(context.Background(), &credentialCache{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ASIA", creds.AccessKeyID)
+	assert.Equal(t, "session", creds.SessionToken)
+}
@@ -0,0 +1,219 @@
+package awsstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/johnmikee/yae"
+	"github.com/johnmikee/yae/internal/sharedread"
+)
+
+/*
+SecretsManagerStore is a yae.SecretStore backed by AWS Secrets Manager.
+
+Secrets are expected to hold a JSON object, which is split into keys: each key
+is a field of the secret named by the service. The key can also be written as
+name#field to read a field of another secret, and as name# to read the whole
+value of a secret that is not JSON, e.g. with a secret tag:
+
+	type Config struct {
+		APIKey   string `json:"api_key"`                 // field api_key of the service secret
+		Password string `secret:"prod/database#password"` // field password of prod/database
+		Cert     string `secret:"prod/tls-cert#"`         // all of prod/tls-cert
+	}
+
+Concurrent lookups of the same secret during a load share a single request.
+Writes replace the secret with the merged fields and are not protected against
+concurrent writers.
+
+A SecretsManagerStore is safe for concurrent use. It must not be copied after
+first use.
+*/
+type SecretsManagerStore struct {
+	Config
+
+	creds credentialCache
+	reads sharedread.Group[string] // GetSecretValue requests in flight by secret ID
+}
+
+// Get returns the field key of the service secret.
+func (s *SecretsManagerStore) Get(service, key string) (string, error) {
+	return s.GetContext(context.Background(), service, key)
+}
+
+// GetContext returns the field key of the service secret, giving up when ctx is done.
+func (s *SecretsManagerStore) GetContext(ctx context.Context, service, key string) (string, error) {
+	id, field := secretsManagerID(service, key)
+
+	value, err := s.reads.Do(ctx, id, func() (string, error) {
+		return s.getSecretValue(ctx, id)
+	})
+	if err != nil {
+		return "", err
+	}
+	if field == "" {
+		return value, nil
+	}
+
+	fields, err := secretsManagerFields(id, value)
+	if err != nil {
+		return "", err
+	}
+	raw, ok := fields[field]
+	if !ok {
+		return "", yae.ErrSecretNotFound
+	}
+
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str, nil
+	}
+	// numbers, booleans and objects are returned as JSON
+	return string(raw), nil
+}
+
+// Set writes value to the field key of the service secret, keeping its other
+// fields. The secret is created if it does not exist.
+func (s *SecretsManagerStore) Set(service, key, value string) error {
+	ctx := context.Background()
+	id, field := secretsManagerID(service, key)
+
+	current, err := s.getSecretValue(ctx, id)
+	if errors.Is(err, yae.ErrSecretNotFound) {
+		if field != "" {
+			value = mustMarshal(map[string]string{field: value})
+		}
+		return s.call(ctx, &s.creds, secretsManager, "secretsmanager.CreateSecret",
+			map[string]string{"Name": id, "SecretString": value}, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	if field != "" {
+		fields, err := secretsManagerFields(id, current)
+		if err != nil {
+			return err
+		}
+		fields[field] = json.RawMessage(mustMarshal(value))
+		value = mustMarshal(fields)
+	}
+	return s.put(ctx, id, value)
+}
+
+// Delete removes the field key from the service secret. A name# key deletes
+// the whole secret, which AWS keeps for its recovery window.
+func (s *SecretsManagerStore) Delete(service, key string) error {
+	ctx := context.Background()
+	id, field := secretsManagerID(service, key)
+
+	if field == "" {
+		err := s.call(ctx, &s.creds, secretsManager, "secretsmanager.DeleteSecret",
+			map[string]string{"SecretId": id}, nil)
+		if errorCode(err) == "ResourceNotFoundException" {
+			return yae.ErrSecretNotFound
+		}
+		return err
+	}
+
+	current, err := s.getSecretValue(ctx, id)
+	if err != nil {
+		return err
+	}
+	fields, err := secretsManagerFields(id, current)
+	if err != nil {
+		return err
+	}
+	if _, ok := fields[field]; !ok {
+		return yae.ErrSecretNotFound
+	}
+	delete(fields, field)
+	return s.put(ctx, id, mustMarshal(fields))
+}
+
+// List returns the sorted fields of the service secret.
+func (s *SecretsManagerStore) List(service string) ([]string, error) {
+	value, err := s.getSecretValue(context.Background(), service)
+	if errors.Is(err, yae.ErrSecretNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := secretsManagerFields(service, value)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// secretsManagerID splits a name#field key, or returns the service as the secret of the field key.
+func secretsManagerID(service, key string) (string, string) {
+	if id, field, ok := strings.Cut(key, "#"); ok {
+		return id, field
+	}
+	return service, key
+}
+
+// secretsManagerFields parses a secret holding a JSON object.
+func secretsManagerFields(id, value string) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return nil, fmt.Errorf("secret %s is not a JSON object, use %s# to read all of it", id, id)
+	}
+	if fields == nil {
+		fields = make(map[string]json.RawMessage)
+	}
+	return fields, nil
+}
+
+// getSecretValue returns the current value of the secret, decoding binary secrets.
+func (s *SecretsManagerStore) getSecretValue(ctx context.Context, id string) (string, error) {
+	var resp struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary []byte  `json:"SecretBinary"`
+	}
+	err := s.call(ctx, &s.creds, secretsManager, "secretsmanager.GetSecretValue",
+		map[string]string{"SecretId": id}, &resp)
+	if errorCode(err) == "ResourceNotFoundException" {
+		return "", yae.ErrSecretNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", id, err)
+	}
+
+	if resp.SecretString != nil {
+		return *resp.SecretString, nil
+	}
+	return base64.StdEncoding.EncodeToString(resp.SecretBinary), nil
+}
+
+// put stores value as the new current version of the secret.
+func (s *SecretsManagerStore) put(ctx context.Context, id, value string) error {
+	err := s.call(ctx, &s.creds, secretsManager, "secretsmanager.PutSecretValue",
+		map[string]string{"SecretId": id, "SecretString": value}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to put secret %s: %w", id, err)
+	}
+	return nil
+}
+
+// mustMarshal encodes v, which must be a string or a map of strings or raw JSON.
+func mustMarshal(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,134 @@
+package awsstore
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/johnmikee/yae"
+)
+
+/*
+SSMStore is a yae.SecretStore backed by AWS Systems Manager Parameter Store.
+
+Each key is the parameter /<Prefix>/<service>/<key>, e.g. /prod/myapp/api_key.
+A key that starts with a slash, e.g. from secret:"/shared/database/password",
+is used as the parameter name as is. SecureString parameters are decrypted
+when read, and Set writes SecureString parameters encrypted with KeyID.
+
+An SSMStore is safe for concurrent use. It must not be copied after first use.
+*/
+type SSMStore struct {
+	Config
+
+	Prefix string // Path prepended to parameter names, e.g. /prod
+	KeyID  string // KMS key for SecureString parameters, defaults to the account's aws/ssm key
+
+	creds credentialCache
+}
+
+// Get returns the decrypted value of the parameter for key.
+func (s *SSMStore) Get(service, key string) (string, error) {
+	return s.GetContext(context.Background(), service, key)
+}
+
+// GetContext returns the decrypted value of the parameter for key, giving up when ctx is done.
+func (s *SSMStore) GetContext(ctx context.Context, service, key string) (string, error) {
+	name := s.name(service, key)
+
+	var resp struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+	err := s.call(ctx, &s.creds, ssm, "AmazonSSM.GetParameter",
+		map[string]interface{}{"Name": name, "WithDecryption": true}, &resp)
+	if errorCode(err) == "ParameterNotFound" {
+		return "", yae.ErrSecretNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get parameter %s: %w", name, err)
+	}
+	return resp.Parameter.Value, nil
+}
+
+// Set writes value to the parameter for key as a SecureString, overwriting it if it exists.
+func (s *SSMStore) Set(service, key, value string) error {
+	name := s.name(service, key)
+
+	req := map[string]interface{}{
+		"Name":      name,
+		"Value":     value,
+		"Type":      "SecureString",
+		"Overwrite": true,
+	}
+	if s.KeyID != "" {
+		req["KeyId"] = s.KeyID
+	}
+	if err := s.call(context.Background(), &s.creds, ssm, "AmazonSSM.PutParameter", req, nil); err != nil {
+		return fmt.Errorf("failed to put parameter %s: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes the parameter for key.
+func (s *SSMStore) Delete(service, key string) error {
+	name := s.name(service, key)
+
+	err := s.call(context.Background(), &s.creds, ssm, "AmazonSSM.DeleteParameter",
+		map[string]string{"Name": name}, nil)
+	if errorCode(err) == "ParameterNotFound" {
+		return yae.ErrSecretNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete parameter %s: %w", name, err)
+	}
+	return nil
+}
+
+// List returns the sorted keys of the parameters under the service path.
+func (s *SSMStore) List(service string) ([]string, error) {
+	dir := s.name(service, "")
+
+	var (
+		keys  []string
+		token string
+	)
+	for {
+		req := map[string]interface{}{"Path": dir, "Recursive": true}
+		if token != "" {
+			req["NextToken"] = token
+		}
+
+		var resp struct {
+			Parameters []struct {
+				Name string `json:"Name"`
+			} `json:"Parameters"`
+			NextToken string `json:"NextToken"`
+		}
+		if err := s.call(context.Background(), &s.creds, ssm, "AmazonSSM.GetParametersByPath", req, &resp); err != nil {
+			return nil, fmt.Errorf("failed to list parameters under %s: %w", dir, err)
+		}
+
+		for _, p := range resp.Parameters {
+			keys = append(keys, strings.TrimPrefix(p.Name, dir+"/"))
+		}
+		if resp.NextToken == "" {
+			break
+		}
+		token = resp.NextToken
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// name returns the parameter name of key, which is absolute if it starts with a slash.
+func (s *SSMStore) name(service, key string) string {
+	if strings.HasPrefix(key, "/") {
+		return key
+	}
+	return path.Join("/", s.Prefix, service, key)
+}
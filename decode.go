@@ -0,0 +1,218 @@
+package yae
+
+import (
+	"encoding"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultSeparator splits slice items and map entries unless a sep tag is set.
+	defaultSeparator = ","
+	// defaultLayout parses time.Time fields unless a layout tag is set.
+	defaultLayout = time.RFC3339
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	urlType      = reflect.TypeOf(url.URL{})
+	ipType       = reflect.TypeOf(net.IP{})
+
+	decoderType         = reflect.TypeOf((*Decoder)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Decoder is implemented by field types that decode themselves from an env or
+// secret store value. It takes precedence over encoding.TextUnmarshaler.
+type Decoder interface {
+	Decode(value string) error
+}
+
+// setField sets the value of a field in the struct based on its type.
+//
+// Slices and maps are decoded from a single value split on the field's sep tag,
+// which defaults to a comma. Map entries are written as key=value, e.g.
+// LABELS=team=x,tier=y.
+//
+// Types implementing Decoder are decoded through it first. time.Duration,
+// time.Time, url.URL and net.IP fields are then parsed with their own parsers,
+// time.Time using the field's layout tag and defaulting to RFC 3339. Any other
+// type implementing encoding.TextUnmarshaler is decoded through it.
+//
+// Pointer fields are allocated and only set once value has been decoded, so a
+// nil pointer means the value was never provided.
+func setField(field reflect.Value, tag reflect.StructTag, value string) error {
+	if !field.CanSet() {
+		return fmt.Errorf("field cannot be set")
+	}
+
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setField(ptr.Elem(), tag, value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	if d, ok := field.Addr().Interface().(Decoder); ok {
+		if err := d.Decode(value); err != nil {
+			return fmt.Errorf("failed to decode value: %w", err)
+		}
+		return nil
+	}
+
+	switch field.Type() {
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse duration value: %w", err)
+		}
+		field.SetInt(int64(d))
+		return nil
+	case timeType:
+		layout := tag.Get("layout")
+		if layout == "" {
+			layout = defaultLayout
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return fmt.Errorf("failed to parse time value: %w", err)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case urlType:
+		u, err := url.Parse(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse url value: %w", err)
+		}
+		field.Set(reflect.ValueOf(*u))
+		return nil
+	case ipType:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return fmt.Errorf("failed to parse ip value: %q", value)
+		}
+		field.Set(reflect.ValueOf(ip))
+		return nil
+	}
+
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("failed to unmarshal text value: %w", err)
+		}
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse integer value: %w", err)
+		}
+		field.SetInt(intValue)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uintValue, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse unsigned integer value: %w", err)
+		}
+		field.SetUint(uintValue)
+	case reflect.Bool:
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse boolean value: %w", err)
+		}
+		field.SetBool(boolValue)
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse float value: %w", err)
+		}
+		field.SetFloat(floatValue)
+	case reflect.Slice:
+		return setSlice(field, tag, value)
+	case reflect.Map:
+		return setMap(field, tag, value)
+	default:
+		return fmt.Errorf("unsupported field type")
+	}
+
+	return nil
+}
+
+func setSlice(field reflect.Value, tag reflect.StructTag, value string) error {
+	items := splitValue(value, separator(tag))
+
+	slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setField(slice.Index(i), tag, item); err != nil {
+			return fmt.Errorf("failed to set item %d: %w", i, err)
+		}
+	}
+
+	field.Set(slice)
+	return nil
+}
+
+func setMap(field reflect.Value, tag reflect.StructTag, value string) error {
+	mapType := field.Type()
+	result := reflect.MakeMap(mapType)
+
+	for _, entry := range splitValue(value, separator(tag)) {
+		k, v, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("invalid map entry %q, expected key=value", entry)
+		}
+
+		key := reflect.New(mapType.Key()).Elem()
+		if err := setField(key, tag, strings.TrimSpace(k)); err != nil {
+			return fmt.Errorf("failed to set map key %q: %w", k, err)
+		}
+
+		val := reflect.New(mapType.Elem()).Elem()
+		if err := setField(val, tag, strings.TrimSpace(v)); err != nil {
+			return fmt.Errorf("failed to set map value for %q: %w", k, err)
+		}
+
+		result.SetMapIndex(key, val)
+	}
+
+	field.Set(result)
+	return nil
+}
+
+// isValueStruct reports whether a struct type is decoded as a single value rather than walked.
+func isValueStruct(t reflect.Type) bool {
+	if t == timeType || t == urlType {
+		return true
+	}
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(decoderType) || ptr.Implements(textUnmarshalerType)
+}
+
+// separator returns the sep tag of a field or the default separator.
+func separator(tag reflect.StructTag) string {
+	if sep := tag.Get("sep"); sep != "" {
+		return sep
+	}
+	return defaultSeparator
+}
+
+// splitValue splits value on sep, trimming whitespace and dropping empty items.
+func splitValue(value, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
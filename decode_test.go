@@ -0,0 +1,196 @@
+package yae
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type decodeConfig struct {
+	String string
+	Int    int
+	Uint   uint16
+	Bool   bool
+	Float  float64
+	Hosts  []string
+	Ports  []int `sep:";"`
+	Labels map[string]string
+	Limits map[string]int `sep:"|"`
+}
+
+// setTestField sets the named field of cfg through setField.
+func setTestField(cfg interface{}, name, value string) error {
+	v := reflect.ValueOf(cfg).Elem()
+	sf, _ := v.Type().FieldByName(name)
+	return setField(v.FieldByName(name), sf.Tag, value)
+}
+
+func TestSetFieldScalars(t *testing.T) {
+	var cfg decodeConfig
+	assert.NoError(t, setTestField(&cfg, "String", "value"))
+	assert.NoError(t, setTestField(&cfg, "Int", "-42"))
+	assert.NoError(t, setTestField(&cfg, "Uint", "42"))
+	assert.NoError(t, setTestField(&cfg, "Bool", "true"))
+	assert.NoError(t, setTestField(&cfg, "Float", "4.2"))
+
+	assert.Equal(t, decodeConfig{String: "value", Int: -42, Uint: 42, Bool: true, Float: 4.2}, cfg)
+
+	assert.Error(t, setTestField(&cfg, "Int", "abc"))
+	assert.Error(t, setTestField(&cfg, "Bool", "maybe"))
+}
+
+func TestSetFieldSlices(t *testing.T) {
+	var cfg decodeConfig
+	assert.NoError(t, setTestField(&cfg, "Hosts", "a, b,c,"))
+	assert.NoError(t, setTestField(&cfg, "Ports", "80;443"))
+
+	assert.Equal(t, []string{"a", "b", "c"}, cfg.Hosts)
+	assert.Equal(t, []int{80, 443}, cfg.Ports)
+
+	assert.Error(t, setTestField(&cfg, "Ports", "80,443"))
+}
+
+func TestSetFieldMaps(t *testing.T) {
+	var cfg decodeConfig
+	assert.NoError(t, setTestField(&cfg, "Labels", "team=x,tier=y,url=a=b"))
+	assert.NoError(t, setTestField(&cfg, "Limits", "cpu=2|memory=512"))
+
+	assert.Equal(t, map[string]string{"team": "x", "tier": "y", "url": "a=b"}, cfg.Labels)
+	assert.Equal(t, map[string]int{"cpu": 2, "memory": 512}, cfg.Limits)
+
+	assert.Error(t, setTestField(&cfg, "Labels", "team"))
+	assert.Error(t, setTestField(&cfg, "Limits", "cpu=two"))
+}
+
+type typedConfig struct {
+	Timeout  time.Duration
+	Cutoff   time.Time
+	Day      time.Time `layout:"2006-01-02"`
+	Endpoint url.URL
+	Bind     net.IP
+	Backoff  []time.Duration
+}
+
+func TestSetFieldTypes(t *testing.T) {
+	var cfg typedConfig
+	assert.NoError(t, setTestField(&cfg, "Timeout", "1m30s"))
+	assert.NoError(t, setTestField(&cfg, "Cutoff", "2023-07-01T12:00:00Z"))
+	assert.NoError(t, setTestField(&cfg, "Day", "2023-07-01"))
+	assert.NoError(t, setTestField(&cfg, "Endpoint", "https://example.com:8443/api?x=1"))
+	assert.NoError(t, setTestField(&cfg, "Bind", "127.0.0.1"))
+	assert.NoError(t, setTestField(&cfg, "Backoff", "1s,2s,4s"))
+
+	assert.Equal(t, 90*time.Second, cfg.Timeout)
+	assert.Equal(t, time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC), cfg.Cutoff)
+	assert.Equal(t, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), cfg.Day)
+	assert.Equal(t, "example.com:8443", cfg.Endpoint.Host)
+	assert.Equal(t, "/api", cfg.Endpoint.Path)
+	assert.True(t, net.IPv4(127, 0, 0, 1).Equal(cfg.Bind))
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, cfg.Backoff)
+
+	assert.Error(t, setTestField(&cfg, "Timeout", "90"))
+	assert.Error(t, setTestField(&cfg, "Day", "07/01/2023"))
+	assert.Error(t, setTestField(&cfg, "Endpoint", "://bad"))
+	assert.Error(t, setTestField(&cfg, "Bind", "not-an-ip"))
+}
+
+func TestSetFieldTypesDevEnv(t *testing.T) {
+	var cfg struct {
+		Timeout  time.Duration `json:"timeout"`
+		Endpoint url.URL       `json:"endpoint"`
+	}
+
+	err := Get(DEV, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        mapStore{"svc": {"timeout": "5s", "endpoint": "https://example.com"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Equal(t, "example.com", cfg.Endpoint.Host)
+}
+
+// logLevel implements encoding.TextUnmarshaler.
+type logLevel int
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*l = -1
+	case "info":
+		*l = 0
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+// accountID implements Decoder.
+type accountID string
+
+func (a *accountID) Decode(value string) error {
+	if !strings.HasPrefix(value, "acct_") {
+		return fmt.Errorf("invalid account id %q", value)
+	}
+	*a = accountID(strings.TrimPrefix(value, "acct_"))
+	return nil
+}
+
+// version is a struct decoded as a single value rather than walked.
+type version struct {
+	Major, Minor int
+}
+
+func (v *version) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d.%d", &v.Major, &v.Minor)
+	return err
+}
+
+// both implements Decoder and encoding.TextUnmarshaler.
+type both string
+
+func (b *both) Decode(value string) error {
+	*b = both("decoder:" + value)
+	return nil
+}
+
+func (b *both) UnmarshalText(text []byte) error {
+	*b = both("text:" + string(text))
+	return nil
+}
+
+func TestSetFieldUnmarshalers(t *testing.T) {
+	var cfg struct {
+		Level   logLevel   `json:"level"`
+		Levels  []logLevel `json:"levels"`
+		Account accountID  `json:"account"`
+		Version version    `json:"version"`
+		Both    both       `json:"both"`
+	}
+
+	assert.NoError(t, setTestField(&cfg, "Level", "debug"))
+	assert.NoError(t, setTestField(&cfg, "Levels", "info,debug"))
+	assert.NoError(t, setTestField(&cfg, "Account", "acct_123"))
+	assert.NoError(t, setTestField(&cfg, "Version", "1.2"))
+	assert.NoError(t, setTestField(&cfg, "Both", "x"))
+
+	assert.Equal(t, logLevel(-1), cfg.Level)
+	assert.Equal(t, []logLevel{0, -1}, cfg.Levels)
+	assert.Equal(t, accountID("123"), cfg.Account)
+	assert.Equal(t, version{1, 2}, cfg.Version)
+	assert.Equal(t, both("decoder:x"), cfg.Both)
+
+	assert.Error(t, setTestField(&cfg, "Level", "verbose"))
+	assert.Error(t, setTestField(&cfg, "Account", "123"))
+
+	// structs implementing an unmarshaler are fields, not nested structs
+	c := &Env{Type: JSON, ConfigStruct: &cfg}
+	assert.Equal(t, []string{"level", "levels", "account", "version", "both"}, c.GetKeys())
+}
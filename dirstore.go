@@ -0,0 +1,185 @@
+package yae
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultSecretsDir is where Docker and Compose mount secrets.
+	defaultSecretsDir = "/run/secrets"
+	// dirStoreWatchInterval is how often Watch polls unless told otherwise.
+	dirStoreWatchInterval = 10 * time.Second
+	// kubeDataLink is the symlink kubelet swaps atomically to update a secret volume.
+	kubeDataLink = "..data"
+)
+
+/*
+DirStore is a read-only SecretStore for secrets mounted as files, one file per
+key: Docker and Compose secrets in /run/secrets, Kubernetes secret volumes and
+systemd credentials in $CREDENTIALS_DIRECTORY.
+
+The file of a key is <Dir>/<key>, and the service is not part of the path
+since the directory already belongs to one application. Dir defaults to
+CREDENTIALS_DIRECTORY, or /run/secrets. Trailing newlines are trimmed from the
+values, as files written by editors and echo end with one.
+
+Set and Delete return ErrReadOnlyStore, and missing secrets are not prompted
+for in dev and local.
+*/
+type DirStore struct {
+	Dir string // Directory the secrets are mounted in
+}
+
+// Get returns the contents of the file of key without trailing newlines.
+func (d *DirStore) Get(service, key string) (string, error) {
+	file, err := d.file(key)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", file, err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// ReadOnly reports true, mounted secrets are managed by the platform.
+func (d *DirStore) ReadOnly() bool {
+	return true
+}
+
+// Set is not supported, mounted secrets are managed by the platform.
+func (d *DirStore) Set(service, key, value string) error {
+	return ErrReadOnlyStore
+}
+
+// Delete is not supported, mounted secrets are managed by the platform.
+func (d *DirStore) Delete(service, key string) error {
+	return ErrReadOnlyStore
+}
+
+// List returns the sorted names of the secret files, skipping hidden files
+// such as the ..data directory of Kubernetes volumes.
+func (d *DirStore) List(service string) ([]string, error) {
+	dir := d.dir()
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets in %s: %w", dir, err)
+	}
+
+	var keys []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		// Kubernetes secrets are symlinks into ..data, so follow them
+		info, err := os.Stat(filepath.Join(dir, e.Name()))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		keys = append(keys, e.Name())
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+/*
+Watch polls the directory every interval, or every 10 seconds if interval is
+not positive, and calls changed when the secrets were updated, until ctx is
+done. It returns the error of ctx.
+
+Kubernetes updates a secret volume by pointing its ..data symlink at a new
+directory, so a change of the link target is one update. Other directories are
+compared by the names, sizes and modification times of their files.
+
+A typical changed func loads the config into a new value and swaps it in:
+
+	go store.Watch(ctx, 0, func() {
+		var next Config
+		if err := loader.Load(yae.PROD, &next); err != nil {
+			log.Print(err)
+			return
+		}
+		current.Store(&next) // an atomic.Pointer[Config]
+	})
+*/
+func (d *DirStore) Watch(ctx context.Context, interval time.Duration, changed func()) error {
+	if interval <= 0 {
+		interval = dirStoreWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := d.snapshot()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if current := d.snapshot(); current != last {
+				last = current
+				changed()
+			}
+		}
+	}
+}
+
+// snapshot describes the current state of the directory, so two snapshots
+// differ when the secrets were updated.
+func (d *DirStore) snapshot() string {
+	dir := d.dir()
+	if target, err := os.Readlink(filepath.Join(dir, kubeDataLink)); err == nil {
+		return kubeDataLink + " -> " + target
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	var b strings.Builder
+	for _, e := range entries {
+		info, err := os.Stat(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// dir returns the directory of the secrets.
+func (d *DirStore) dir() string {
+	if d.Dir != "" {
+		return d.Dir
+	}
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+		return dir
+	}
+	return defaultSecretsDir
+}
+
+// file returns the file of key, which must stay inside the directory.
+func (d *DirStore) file(key string) (string, error) {
+	dir := filepath.Clean(d.dir())
+	file := filepath.Join(dir, key)
+	if !strings.HasPrefix(file, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("secret file %s is outside of %s", key, dir)
+	}
+	return file, nil
+}
@@ -0,0 +1,134 @@
+package yae_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/johnmikee/yae"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeKubeSecret lays out a secret volume the way kubelet does: the files
+// live in a timestamped directory that ..data points to, and each key is a
+// symlink into ..data. Writing a new version swaps ..data atomically.
+func writeKubeSecret(t *testing.T, dir, version string, data map[string]string) {
+	versionDir := filepath.Join(dir, ".."+version)
+	assert.NoError(t, os.Mkdir(versionDir, 0o755))
+	for k, v := range data {
+		assert.NoError(t, os.WriteFile(filepath.Join(versionDir, k), []byte(v), 0o644))
+		// the link already exists from an earlier version
+		os.Symlink(filepath.Join("..data", k), filepath.Join(dir, k))
+	}
+
+	tmp := filepath.Join(dir, "..data_tmp")
+	assert.NoError(t, os.Symlink(filepath.Base(versionDir), tmp))
+	assert.NoError(t, os.Rename(tmp, filepath.Join(dir, "..data")))
+}
+
+func TestDirStore(t *testing.T) {
+	dir := t.TempDir()
+	writeKubeSecret(t, dir, "v1", map[string]string{"api_key": "abc123\n", "token": "t0k3n\r\n\n"})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"), []byte("line 1\nline 2\n"), 0o600))
+	store := &yae.DirStore{Dir: dir}
+
+	value, err := store.Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+
+	value, err = store.Get("myapp", "token")
+	assert.NoError(t, err)
+	assert.Equal(t, "t0k3n", value)
+
+	value, err = store.Get("myapp", "cert.pem")
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2", value)
+
+	_, err = store.Get("myapp", "missing")
+	assert.ErrorIs(t, err, yae.ErrSecretNotFound)
+	_, err = store.Get("myapp", "../escape")
+	assert.ErrorContains(t, err, "outside of")
+
+	keys, err := store.List("myapp")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "cert.pem", "token"}, keys)
+
+	assert.ErrorIs(t, store.Set("myapp", "api_key", "new"), yae.ErrReadOnlyStore)
+	assert.ErrorIs(t, store.Delete("myapp", "api_key"), yae.ErrReadOnlyStore)
+
+	// systemd credentials
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	value, err = (&yae.DirStore{}).Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+}
+
+func TestDirStoreWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeKubeSecret(t, dir, "v1", map[string]string{"api_key": "abc123"})
+	store := &yae.DirStore{Dir: dir}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 1)
+	done := make(chan error)
+	go func() {
+		done <- store.Watch(ctx, 10*time.Millisecond, func() { changed <- struct{}{} })
+	}()
+
+	// let the watcher take its first snapshot before updating the secret
+	time.Sleep(50 * time.Millisecond)
+	writeKubeSecret(t, dir, "v2", map[string]string{"api_key": "rotated"})
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the ..data swap was not noticed")
+	}
+
+	value, err := store.Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated", value)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDirStoreLoad(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "api_key"), []byte("abc123\n"), 0o600))
+
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Token  string `json:"token" yae:"optional"`
+	}
+
+	var conf Conf
+	err := yae.New(
+		yae.WithFile("myapp.json", yae.JSON),
+		yae.WithSecretStore(&yae.DirStore{Dir: dir}),
+	).Load(yae.PROD, &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, Conf{APIKey: "abc123"}, conf)
+}
+
+func TestDirStoreLoadMissing(t *testing.T) {
+	dir := t.TempDir()
+
+	type Conf struct {
+		APIKey string `json:"api_key"`
+	}
+
+	// a read-only store is not prompted for the missing secret
+	var conf Conf
+	err := yae.New(
+		yae.WithFile("myapp.json", yae.JSON),
+		yae.WithSecretStore(&yae.DirStore{Dir: dir}),
+	).Load(yae.DEV, &conf)
+
+	var missing *yae.MissingEnvError
+	if assert.ErrorAs(t, err, &missing) {
+		assert.Equal(t, []string{"APIKey"}, missing.Fields)
+	}
+}
@@ -0,0 +1,196 @@
+package yae
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+dotenv files hold KEY=VALUE pairs, one per line. The parser understands:
+  - comments: lines starting with # and trailing " # comment" on unquoted values
+  - an optional "export " prefix before the key
+  - single quoted values, which are taken literally
+  - double quoted values, which expand \n, \r, \t, \", \\ and \$ escapes
+  - quoted values spanning multiple lines
+*/
+
+// ParseDotEnv parses the KEY=VALUE pairs of a dotenv file.
+func ParseDotEnv(r io.Reader) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dotenv: %w", err)
+	}
+
+	p := &dotenvParser{src: strings.ReplaceAll(string(data), "\r\n", "\n"), line: 1}
+	return p.parse()
+}
+
+type dotenvParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *dotenvParser) parse() (map[string]string, error) {
+	result := make(map[string]string)
+
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return result, nil
+		}
+
+		switch p.peek() {
+		case '\n':
+			p.next()
+			continue
+		case '#':
+			p.skipLine()
+			continue
+		}
+
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		result[key] = value
+	}
+}
+
+func (p *dotenvParser) key() (string, error) {
+	line := p.line
+	key := p.ident()
+	if key == "export" && !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.skipSpace()
+		key = p.ident()
+	}
+	if key == "" {
+		return "", fmt.Errorf("dotenv line %d: expected a key", line)
+	}
+
+	p.skipSpace()
+	if p.eof() || p.peek() != '=' {
+		return "", fmt.Errorf("dotenv line %d: expected '=' after %s", line, key)
+	}
+	p.next()
+	p.skipSpace()
+
+	return key, nil
+}
+
+func (p *dotenvParser) value() (string, error) {
+	if p.eof() {
+		return "", nil
+	}
+
+	switch q := p.peek(); q {
+	case '\'', '"':
+		line := p.line
+		p.next()
+		value, err := p.quoted(q)
+		if err != nil {
+			return "", fmt.Errorf("dotenv line %d: %w", line, err)
+		}
+		// only whitespace or a comment may follow the closing quote
+		p.skipSpace()
+		if !p.eof() && p.peek() != '\n' && p.peek() != '#' {
+			return "", fmt.Errorf("dotenv line %d: unexpected characters after quoted value", p.line)
+		}
+		p.skipLine()
+		return value, nil
+	default:
+		start := p.pos
+		p.skipLine()
+		value := strings.TrimSuffix(p.src[start:p.pos], "\n")
+		// an inline comment must be separated from the value by whitespace
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		} else if i := strings.Index(value, "\t#"); i >= 0 {
+			value = value[:i]
+		}
+		return strings.TrimSpace(value), nil
+	}
+}
+
+func (p *dotenvParser) quoted(q byte) (string, error) {
+	var sb strings.Builder
+	for !p.eof() {
+		ch := p.next()
+		switch {
+		case ch == q:
+			return sb.String(), nil
+		case ch == '\\' && q == '"' && !p.eof():
+			esc := p.next()
+			switch esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\', '$':
+				sb.WriteByte(esc)
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(esc)
+			}
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return "", fmt.Errorf("unterminated %c quoted value", q)
+}
+
+func (p *dotenvParser) ident() string {
+	start := p.pos
+	for !p.eof() {
+		ch := p.peek()
+		if ch == '_' || ch == '.' || ch == '-' ||
+			(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
+			(ch >= '0' && ch <= '9' && p.pos > start) {
+			p.next()
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *dotenvParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine advances past the next newline.
+func (p *dotenvParser) skipLine() {
+	for !p.eof() {
+		if p.next() == '\n' {
+			return
+		}
+	}
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *dotenvParser) peek() byte {
+	return p.src[p.pos]
+}
+
+func (p *dotenvParser) next() byte {
+	ch := p.src[p.pos]
+	p.pos++
+	if ch == '\n' {
+		p.line++
+	}
+	return ch
+}
@@ -0,0 +1,84 @@
+package yae_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/johnmikee/yae"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	content := `# a comment
+export API_KEY=abc123
+DATABASE_URL = postgres://localhost:5432/db # trailing comment
+EMPTY=
+HASH=value#not-a-comment
+SINGLE='literal \n $HOME # kept'
+DOUBLE="line1\nline2 \"quoted\""
+MULTI="first
+second"
+SPACED=  padded value  
+
+  INDENTED=yes
+`
+
+	vars, err := yae.ParseDotEnv(strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"API_KEY":      "abc123",
+		"DATABASE_URL": "postgres://localhost:5432/db",
+		"EMPTY":        "",
+		"HASH":         "value#not-a-comment",
+		"SINGLE":       `literal \n $HOME # kept`,
+		"DOUBLE":       "line1\nline2 \"quoted\"",
+		"MULTI":        "first\nsecond",
+		"SPACED":       "padded value",
+		"INDENTED":     "yes",
+	}, vars)
+}
+
+func TestParseDotEnvErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing equals":   "KEY value",
+		"unterminated":     "KEY=\"value\nOTHER=1",
+		"trailing garbage": "KEY='value' extra",
+		"missing key":      "=value",
+		"bare export":      "export",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := yae.ParseDotEnv(strings.NewReader(content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte(`export YAE_API_KEY="secret-api-key"
+YAE_DATABASE_URL=https://example.com/db
+API_KEY=not-prefixed
+`)
+	if err := os.WriteFile(filepath.Join(dir, ".env"), content, 0o644); err != nil {
+		t.Fatalf("failed to create dotenv file: %v", err)
+	}
+
+	var appConfig AppConfig
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         ".env",
+			Path:         dir,
+			Type:         yae.DOTENV,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &appConfig,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret-api-key", appConfig.APIKey)
+	assert.Equal(t, "https://example.com/db", appConfig.DatabaseURL)
+}
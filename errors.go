@@ -0,0 +1,65 @@
+package yae
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidConfigStruct is returned when Env.ConfigStruct is not a non-nil pointer to a struct.
+var ErrInvalidConfigStruct = errors.New("config struct must be a non-nil pointer to a struct")
+
+// MissingEnvError is returned when required fields were not found in the environment.
+type MissingEnvError struct {
+	Names  []string // env variable names that were not set
+	Fields []string // paths of the fields they belong to, e.g. Database.Host
+}
+
+func (e *MissingEnvError) Error() string {
+	return fmt.Sprintf("env not found: %s", strings.Join(e.Names, ", "))
+}
+
+// FieldError is returned when a value could not be set on a field. Err is the
+// underlying error, such as a *strconv.NumError or an error from the secret store.
+type FieldError struct {
+	Path   string // path of the field, e.g. Database.Port
+	Source Source // layer the value came from
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("failed to set field %s from %s: %s", e.Path, e.Source, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when a field breaks a rule of its validate tag.
+type ValidationError struct {
+	Path string // path of the field, e.g. Server.Port
+	Rule string // rule that failed, e.g. max
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("field %s failed %s validation: %s", e.Path, e.Rule, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// HookError is returned when a hook of the config struct fails, see Defaulter.
+type HookError struct {
+	Hook string // name of the hook, e.g. HookNormalize
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook failed: %s", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
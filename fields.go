@@ -0,0 +1,267 @@
+package yae
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// field is a settable leaf of the config struct.
+type field struct {
+	root  reflect.Value // the config struct the field belongs to
+	index []int         // field indexes from root, dereferencing pointers on the way
+	sf    reflect.StructField
+	path  string   // Go field path, e.g. Database.Host
+	key   string   // secret store key, e.g. database.host; empty if the field has none
+	env   string   // environment variable name, e.g. PREFIX_DATABASE_HOST
+	names []string // config file key of each path segment, the config type tag or the Go name
+}
+
+// checkConfigStruct returns ErrInvalidConfigStruct unless ConfigStruct is a non-nil pointer to a struct.
+func (c *Env) checkConfigStruct() error {
+	ptr := reflect.ValueOf(c.ConfigStruct)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w, got %T", ErrInvalidConfigStruct, c.ConfigStruct)
+	}
+	return nil
+}
+
+// fields walks the config struct, descending into nested structs, and returns its leaves.
+func (c *Env) fields() []field {
+	if c.checkConfigStruct() != nil {
+		return nil
+	}
+
+	root := reflect.ValueOf(c.ConfigStruct).Elem()
+	w := &fieldWalker{configType: c.Type}
+
+	fields := w.walk(root.Type(), nil, nil, "", "", c.EnvPrefix, false)
+	for i := range fields {
+		fields[i].root = root
+	}
+	return fields
+}
+
+// value returns the current value of the field. It returns false when a
+// pointer to a parent struct is nil, in which case the field is unset.
+func (f field) value() (reflect.Value, bool) {
+	v := f.root
+	for _, i := range f.index {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+// set decodes value into the field. Nil pointers to parent structs are only
+// allocated once the value has been decoded successfully.
+func (f field) set(value string) error {
+	var nilPtr, alloc reflect.Value
+
+	v := f.root
+	for _, i := range f.index {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				p := reflect.New(v.Type().Elem())
+				if nilPtr.IsValid() {
+					// v lives inside the pending allocation so it can be set right away
+					v.Set(p)
+				} else {
+					nilPtr, alloc = v, p
+				}
+				v = p
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+
+	if err := setField(v, f.sf.Tag, value); err != nil {
+		return err
+	}
+	if nilPtr.IsValid() {
+		nilPtr.Set(alloc)
+	}
+	return nil
+}
+
+// defaultValue returns the value of the field's default tag. An empty default
+// leaves the zero value in place but still marks the field as having one.
+func (f field) defaultValue() (string, bool) {
+	return f.sf.Tag.Lookup("default")
+}
+
+// optional reports whether the field may be left unset. Fields are required
+// unless they have a default or are tagged yae:"optional"; yae:"required"
+// always marks a field as required.
+func (f field) optional() bool {
+	if hasOption(f.sf, "required") {
+		return false
+	}
+	if _, ok := f.defaultValue(); ok {
+		return true
+	}
+	return hasOption(f.sf, "optional")
+}
+
+// secret reports whether the field is tagged yae:"secret" and its value must never be shown.
+func (f field) secret() bool {
+	return hasOption(f.sf, "secret")
+}
+
+// public reports whether the field is tagged yae:"public" and its value may be shown.
+func (f field) public() bool {
+	return hasOption(f.sf, "public")
+}
+
+// hasOption reports whether the field's yae tag contains option.
+func hasOption(sf reflect.StructField, option string) bool {
+	for _, o := range strings.Split(sf.Tag.Get("yae"), ",") {
+		if strings.TrimSpace(o) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldWalker collects the leaves of a struct type.
+type fieldWalker struct {
+	configType ConfigType
+	parents    []reflect.Type // struct types being walked, to stop on recursive types
+}
+
+/*
+walk builds the path, key and env name of every leaf below the struct type t.
+
+Nested names are composed from the parent names: the env name of Host in
+Database struct{ Host string } is PREFIX_DATABASE_HOST and its key is
+database.host. Top level fields keep their original naming, so an untagged
+top level field is looked up by its Go name in the env and has no key.
+Embedded structs without a tag are flattened into their parent, and pointers
+to structs are walked like the structs they point to. A secret tag replaces the
+key of a field as is, e.g. secret:"database/prod#password".
+*/
+func (w *fieldWalker) walk(t reflect.Type, index []int, names []string, path, key, env string, nested bool) []field {
+	var fields []field
+
+	w.parents = append(w.parents, t)
+	defer func() { w.parents = w.parents[:len(w.parents)-1] }()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if tagName(sf, string(w.configType)) == "-" {
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+		parent := w.isNested(sf.Type)
+
+		if sf.Anonymous && parent && tagName(sf, string(w.configType)) == "" {
+			if !sf.IsExported() && sf.Type.Kind() == reflect.Pointer {
+				// reflect cannot allocate an embedded pointer to an unexported type
+				continue
+			}
+			fields = append(fields, w.walk(indirect(sf.Type), fieldIndex, names, path, key, env, nested)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		envSeg := envSegment(sf, w.configType)
+		keySeg := tagName(sf, string(w.configType))
+		if nested || parent {
+			envSeg = strings.ToUpper(envSeg)
+			if keySeg == "" {
+				keySeg = strings.ToLower(sf.Name)
+			}
+		}
+
+		fileSeg := tagName(sf, string(w.configType))
+		if fileSeg == "" {
+			fileSeg = sf.Name
+			if strings.EqualFold(string(w.configType), string(YAML)) {
+				// yaml.v2 lowercases untagged field names
+				fileSeg = strings.ToLower(sf.Name)
+			}
+		}
+
+		f := field{
+			index: fieldIndex,
+			sf:    sf,
+			path:  joinName(path, sf.Name, "."),
+			key:   keySeg,
+			env:   joinName(env, envSeg, "_"),
+			names: append(append([]string{}, names...), fileSeg),
+		}
+		if keySeg != "" {
+			f.key = joinName(key, keySeg, ".")
+		}
+
+		if parent {
+			fields = append(fields, w.walk(indirect(sf.Type), f.index, f.names, f.path, f.key, f.env, true)...)
+			continue
+		}
+		if secretKey := sf.Tag.Get("secret"); secretKey != "" {
+			f.key = secretKey
+		}
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// isNested reports whether fields of type t, or of the struct t points to, should be walked into.
+func (w *fieldWalker) isNested(t reflect.Type) bool {
+	t = indirect(t)
+	if t.Kind() != reflect.Struct || isValueStruct(t) {
+		return false
+	}
+	for _, p := range w.parents {
+		if p == t {
+			return false
+		}
+	}
+	return true
+}
+
+// indirect returns the type t points to, or t if it is not a pointer.
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// envSegment returns the env name for a single field, without any prefix.
+func envSegment(sf reflect.StructField, configType ConfigType) string {
+	if tag := tagName(sf, string(configType)); tag != "" {
+		return strings.ToUpper(tag)
+	} else if tag := tagName(sf, "yaml"); tag != "" {
+		return strings.ToUpper(tag)
+	} else if tag := tagName(sf, "env"); tag != "" {
+		return strings.ToUpper(tag)
+	}
+	return sf.Name
+}
+
+// tagName returns the name portion of a struct tag, dropping options such as omitempty.
+func tagName(sf reflect.StructField, tag string) string {
+	if tag == "" {
+		return ""
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get(tag), ",")
+	return name
+}
+
+func joinName(parent, name, sep string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + sep + name
+}
@@ -0,0 +1,128 @@
+package yae
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type nestedDatabase struct {
+	Host string
+	Port int `json:"port"`
+}
+
+type nestedEmbedded struct {
+	Region string `json:"region"`
+}
+
+type nestedConfig struct {
+	nestedEmbedded
+	APIKey   string         `json:"api_key,omitempty"`
+	Name     string         // untagged top level fields keep their legacy naming
+	Ignored  string         `json:"-"`
+	Database nestedDatabase // untagged parents use their field name
+	Cache    struct {
+		TTL int `json:"ttl" env:"cache_ttl"`
+	} `json:"cache"`
+	hidden string //nolint:unused
+}
+
+func TestFields(t *testing.T) {
+	c := &Env{Type: JSON, EnvPrefix: "APP", ConfigStruct: &nestedConfig{}}
+
+	type want struct{ path, key, env string }
+	var got []want
+	for _, f := range c.fields() {
+		got = append(got, want{f.path, f.key, f.env})
+	}
+
+	assert.Equal(t, []want{
+		{"Region", "region", "APP_REGION"},
+		{"APIKey", "api_key", "APP_API_KEY"},
+		{"Name", "", "APP_Name"},
+		{"Database.Host", "database.host", "APP_DATABASE_HOST"},
+		{"Database.Port", "database.port", "APP_DATABASE_PORT"},
+		{"Cache.TTL", "cache.ttl", "APP_CACHE_TTL"},
+	}, got)
+}
+
+func TestNestedDevEnv(t *testing.T) {
+	var cfg nestedConfig
+	store := mapStore{"svc": {
+		"api_key":       "abc123",
+		"database.host": "db.internal",
+		"database.port": "5432",
+		"cache.ttl":     "60",
+	}}
+
+	c := &Env{Name: "svc", Type: JSON, ConfigStruct: &cfg, Store: store, SkipFields: []string{"Region"}}
+	assert.Equal(t, []string{"api_key", "database.host", "database.port", "cache.ttl"}, c.GetKeys())
+
+	err := Get(DEV, c)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 5432, cfg.Database.Port)
+	assert.Equal(t, 60, cfg.Cache.TTL)
+}
+
+type pointerConfig struct {
+	Port    *int           `json:"port"`
+	Enabled *bool          `json:"enabled"`
+	Name    *string        `json:"name"`
+	Timeout *time.Duration `json:"timeout"`
+	DB      *struct {
+		Host string `json:"host"`
+	} `json:"db"`
+	Next *pointerConfig `json:"next"`
+}
+
+func TestPointerFields(t *testing.T) {
+	var cfg pointerConfig
+	c := &Env{Type: JSON, ConfigStruct: &cfg}
+	assert.Equal(t, []string{"port", "enabled", "name", "timeout", "db.host", "next"}, c.GetKeys())
+
+	secrets := &Secrets{
+		{Name: "port", Value: "0"},
+		{Name: "enabled", Value: "false"},
+	}
+	err := BuildDevEnv(c, secrets)
+	assert.NoError(t, err)
+
+	// explicit zero values are set
+	if assert.NotNil(t, cfg.Port) {
+		assert.Equal(t, 0, *cfg.Port)
+	}
+	if assert.NotNil(t, cfg.Enabled) {
+		assert.False(t, *cfg.Enabled)
+	}
+	// missing values stay nil, including parents of nested fields
+	assert.Nil(t, cfg.Name)
+	assert.Nil(t, cfg.Timeout)
+	assert.Nil(t, cfg.DB)
+	assert.Nil(t, cfg.Next)
+
+	secrets = &Secrets{
+		{Name: "timeout", Value: "5s"},
+		{Name: "db.host", Value: "db.internal"},
+	}
+	err = BuildDevEnv(c, secrets)
+	assert.NoError(t, err)
+	if assert.NotNil(t, cfg.Timeout) {
+		assert.Equal(t, 5*time.Second, *cfg.Timeout)
+	}
+	if assert.NotNil(t, cfg.DB) {
+		assert.Equal(t, "db.internal", cfg.DB.Host)
+	}
+}
+
+func TestPointerFieldInvalid(t *testing.T) {
+	var cfg pointerConfig
+	c := &Env{Type: JSON, ConfigStruct: &cfg}
+
+	// parents and pointers are not allocated when the value fails to decode
+	err := BuildDevEnv(c, &Secrets{{Name: "port", Value: "abc"}})
+	assert.Error(t, err)
+	assert.Nil(t, cfg.Port)
+}
@@ -0,0 +1,364 @@
+package yae
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// FileStoreKeyEnv holds the base64 encoded 32 byte key of a FileStore.
+	FileStoreKeyEnv = "YAE_SECRETS_KEY"
+	// FileStorePassphraseEnv holds the passphrase a FileStore key is derived from.
+	FileStorePassphraseEnv = "YAE_SECRETS_PASSPHRASE"
+
+	fileStoreVersion = 1
+	fileStoreKeySize = 32
+	fileStoreSalt    = 16
+
+	// scrypt parameters recommended for interactive logins
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+/*
+FileStore is a SecretStore that keeps secrets in a file encrypted with AES-GCM.
+It is meant for machines without a keyring, such as CI runners and SSH-only
+dev boxes, where go-keyring has no Secret Service to talk to.
+
+The file defaults to $XDG_DATA_HOME/yae/secrets.enc, or
+~/.local/share/yae/secrets.enc, and is written atomically with mode 0600.
+
+The encryption key is, in order of precedence, Key, the base64 encoded key in
+YAE_SECRETS_KEY, or derived with scrypt from Passphrase or
+YAE_SECRETS_PASSPHRASE. The scrypt salt is stored in the file.
+
+A FileStore is safe for concurrent use. Set and Delete hold an advisory lock
+on <file>.lock while they rewrite the file, so processes sharing it, such as
+parallel CI jobs, do not lose each other's writes. It must not be copied after
+first use.
+*/
+type FileStore struct {
+	Path       string // Path to the secrets file
+	Key        []byte // 32 byte AES-256 key
+	Passphrase string // Passphrase the key is derived from when Key is not set
+
+	mu      sync.Mutex
+	derived map[string][]byte // keys derived from a passphrase and salt
+}
+
+// fileStoreData is the on-disk format of a FileStore.
+type fileStoreData struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"` // encrypted JSON of service to key to value
+}
+
+// Get returns the value of key for service from the secrets file.
+func (s *FileStore) Get(service, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, _, err := s.read()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secrets[service][key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// Set stores value under key for service, creating the secrets file if needed.
+func (s *FileStore) Set(service, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, salt, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if secrets[service] == nil {
+		secrets[service] = make(map[string]string)
+	}
+	secrets[service][key] = value
+	return s.write(secrets, salt)
+}
+
+// Delete removes key for service from the secrets file.
+func (s *FileStore) Delete(service, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, salt, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[service][key]; !ok {
+		return ErrSecretNotFound
+	}
+	delete(secrets[service], key)
+	if len(secrets[service]) == 0 {
+		delete(secrets, service)
+	}
+	return s.write(secrets, salt)
+}
+
+// List returns the sorted keys stored for service.
+func (s *FileStore) List(service string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, _, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(secrets[service]))
+	for k := range secrets[service] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// path returns the secrets file, defaulting to yae/secrets.enc in the XDG data directory.
+func (s *FileStore) path() (string, error) {
+	if s.Path != "" {
+		return s.Path, nil
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find the secrets file: %w", err)
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dataHome, "yae", "secrets.enc"), nil
+}
+
+// lock takes the lock that other processes writing the secrets file honor,
+// returning the func that releases it.
+func (s *FileStore) lock() (func(), error) {
+	path, err := s.path()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+	}
+
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", f.Name(), err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// read decrypts the secrets file. A missing file holds no secrets and gets a new salt.
+func (s *FileStore) read() (map[string]map[string]string, []byte, error) {
+	path, err := s.path()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, fileStoreSalt)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		return make(map[string]map[string]string), salt, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var data fileStoreData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse secrets file %s: %w", path, err)
+	}
+	if data.Version != fileStoreVersion {
+		return nil, nil, fmt.Errorf("unsupported secrets file version %d", data.Version)
+	}
+
+	gcm, err := s.cipher(data.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, err := gcm.Open(nil, data.Nonce, data.Data, data.Salt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt secrets file %s, the key is wrong or the file is corrupted", path)
+	}
+
+	secrets := make(map[string]map[string]string)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse secrets: %w", err)
+	}
+	return secrets, data.Salt, nil
+}
+
+// write encrypts the secrets with a new nonce and atomically replaces the secrets file.
+func (s *FileStore) write(secrets map[string]map[string]string, salt []byte) error {
+	path, err := s.path()
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+
+	gcm, err := s.cipher(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	raw, err := json.Marshal(fileStoreData{
+		Version: fileStoreVersion,
+		Salt:    salt,
+		Nonce:   nonce,
+		Data:    gcm.Seal(nil, nonce, plain, salt),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets file: %w", err)
+	}
+
+	return writeFileAtomic(path, raw)
+}
+
+// cipher returns the AES-GCM cipher for the key of the store.
+func (s *FileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := s.key(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// key returns the encryption key, deriving it from the passphrase and salt if needed.
+func (s *FileStore) key(salt []byte) ([]byte, error) {
+	if len(s.Key) > 0 {
+		if len(s.Key) != fileStoreKeySize {
+			return nil, fmt.Errorf("secrets key must be %d bytes, got %d", fileStoreKeySize, len(s.Key))
+		}
+		return s.Key, nil
+	}
+
+	if env := os.Getenv(FileStoreKeyEnv); env != "" {
+		key, err := base64.StdEncoding.DecodeString(env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", FileStoreKeyEnv, err)
+		}
+		if len(key) != fileStoreKeySize {
+			return nil, fmt.Errorf("%s must be %d bytes, got %d", FileStoreKeyEnv, fileStoreKeySize, len(key))
+		}
+		return key, nil
+	}
+
+	passphrase := s.Passphrase
+	if passphrase == "" {
+		passphrase = os.Getenv(FileStorePassphraseEnv)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("no secrets key: set Key or Passphrase, %s or %s", FileStoreKeyEnv, FileStorePassphraseEnv)
+	}
+
+	// scrypt is slow on purpose, so derived keys are kept for the life of the store
+	cacheKey := passphrase + "\x00" + string(salt)
+	if key, ok := s.derived[cacheKey]; ok {
+		return key, nil
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, fileStoreKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive secrets key: %w", err)
+	}
+	if s.derived == nil {
+		s.derived = make(map[string][]byte)
+	}
+	s.derived[cacheKey] = key
+	return key, nil
+}
+
+// writeFileAtomic writes data to a temporary file with mode 0600 next to path
+// and renames it over path, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set file mode: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package yae
+
+import "os"
+
+// lockFile does nothing on platforms without file locks, where a FileStore
+// is only safe for concurrent use within a process.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing on platforms without file locks.
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,107 @@
+package yae_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/johnmikee/yae"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	store := &yae.FileStore{Passphrase: "correct horse battery staple"}
+
+	_, err := store.Get("svc", "api_key")
+	assert.ErrorIs(t, err, yae.ErrSecretNotFound)
+
+	assert.NoError(t, store.Set("svc", "api_key", "abc123"))
+	assert.NoError(t, store.Set("svc", "token", "xyz"))
+	assert.NoError(t, store.Set("other", "api_key", "other"))
+
+	value, err := store.Get("svc", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+
+	keys, err := store.List("svc")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "token"}, keys)
+
+	assert.NoError(t, store.Delete("svc", "token"))
+	assert.ErrorIs(t, store.Delete("svc", "token"), yae.ErrSecretNotFound)
+
+	// the file is private and does not contain the secrets in plain text
+	path := filepath.Join(os.Getenv("XDG_DATA_HOME"), "yae", "secrets.enc")
+	info, err := os.Stat(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+	raw, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(raw, []byte("abc123")))
+
+	// a new store with the same passphrase reads the file, a wrong one cannot
+	value, err = (&yae.FileStore{Passphrase: "correct horse battery staple"}).Get("other", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "other", value)
+
+	_, err = (&yae.FileStore{Passphrase: "wrong"}).Get("other", "api_key")
+	assert.ErrorContains(t, err, "the key is wrong")
+}
+
+func TestFileStoreKeyEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	t.Setenv(yae.FileStoreKeyEnv, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32)))
+
+	store := &yae.FileStore{Path: path}
+	assert.NoError(t, store.Set("svc", "api_key", "abc123"))
+
+	type Conf struct {
+		APIKey string `json:"api_key"`
+	}
+
+	var cfg Conf
+	err := yae.Get(yae.PROD, &yae.Env{
+		Service:      "svc",
+		Type:         yae.JSON,
+		ConfigStruct: &cfg,
+		Store:        &yae.FileStore{Path: path},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+
+	_, err = (&yae.FileStore{Path: path, Key: []byte("short")}).Get("svc", "api_key")
+	assert.ErrorContains(t, err, "must be 32 bytes")
+
+	t.Setenv(yae.FileStoreKeyEnv, "")
+	_, err = (&yae.FileStore{Path: path}).Get("svc", "api_key")
+	assert.ErrorContains(t, err, "no secrets key")
+}
+
+func TestFileStoreConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	key := bytes.Repeat([]byte{7}, 32)
+
+	// separate stores stand in for separate processes, they only share the lock file
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		store := &yae.FileStore{Path: path, Key: key}
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 10; i++ {
+				assert.NoError(t, store.Set("svc", fmt.Sprintf("key%d_%d", w, i), "value"))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	keys, err := (&yae.FileStore{Path: path, Key: key}).List("svc")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 40)
+}
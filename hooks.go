@@ -0,0 +1,104 @@
+package yae
+
+import "reflect"
+
+// Names of the hooks reported in a HookError.
+const (
+	HookSetDefaults = "SetDefaults"
+	HookNormalize   = "Normalize"
+	HookValidate    = "Validate"
+	HookAfterLoad   = "AfterLoad"
+)
+
+/*
+The config struct can take part in a load by implementing any of the hooks
+below. They are called in this order:
+
+	default tags, SetDefaults, sources, Normalize, validate tags, Validate, AfterLoad
+
+An error returned by a hook is wrapped in a *HookError naming the hook.
+*/
+
+// Defaulter is implemented by config structs that set their own defaults. It is
+// called after the default tags and before any source, so every source can
+// override the values it sets.
+type Defaulter interface {
+	SetDefaults() error
+}
+
+// Normalizer is implemented by config structs that clean up their values, e.g.
+// trim or lowercase them, once every source has been applied. It is called
+// before validation, even when the load failed, so fields may be unset.
+type Normalizer interface {
+	Normalize() error
+}
+
+// Validator is implemented by config structs that check themselves once every
+// source has been applied and every validate tag has passed.
+type Validator interface {
+	Validate() error
+}
+
+// AfterLoader is implemented by config structs that need to know how they were
+// loaded. It is called last, and only when the load succeeded.
+type AfterLoader interface {
+	AfterLoad(meta LoadMeta) error
+}
+
+// LoadMeta describes a successful load.
+type LoadMeta struct {
+	EnvType    EnvType    // environment the config was loaded for
+	File       string     // config file that was read, empty if there was none
+	Provenance Provenance // origin of every field that was set
+}
+
+// setDefaults calls the SetDefaults hook. Fields it changes are recorded as
+// defaults, so they count as provided.
+func (l *layers) setDefaults() {
+	d, ok := l.c.ConfigStruct.(Defaulter)
+	if !ok {
+		return
+	}
+
+	before := make([]interface{}, len(l.fields))
+	for i, f := range l.fields {
+		if v, ok := f.value(); ok {
+			before[i] = v.Interface()
+		}
+	}
+
+	if err := d.SetDefaults(); err != nil {
+		l.errs = append(l.errs, &HookError{Hook: HookSetDefaults, Err: err})
+		return
+	}
+
+	for i, f := range l.fields {
+		v, ok := f.value()
+		if !ok || (before[i] == nil && v.IsZero()) || reflect.DeepEqual(before[i], v.Interface()) {
+			continue
+		}
+		l.record(f, Origin{Source: SourceDefault, Name: HookSetDefaults})
+	}
+}
+
+// normalize calls the Normalize hook.
+func (l *layers) normalize() {
+	if n, ok := l.c.ConfigStruct.(Normalizer); ok {
+		if err := n.Normalize(); err != nil {
+			l.errs = append(l.errs, &HookError{Hook: HookNormalize, Err: err})
+		}
+	}
+}
+
+// afterLoad calls the AfterLoad hook when nothing failed.
+func (l *layers) afterLoad() {
+	if len(l.errs) > 0 {
+		return
+	}
+	if a, ok := l.c.ConfigStruct.(AfterLoader); ok {
+		meta := LoadMeta{EnvType: l.envType, File: l.configFile, Provenance: l.origins}
+		if err := a.AfterLoad(meta); err != nil {
+			l.errs = append(l.errs, &HookError{Hook: HookAfterLoad, Err: err})
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package sharedread lets concurrent lookups of the same resource, e.g. the
+// secret that holds several keys of a struct, share a single request.
+package sharedread
+
+import (
+	"context"
+	"sync"
+)
+
+// Group holds the reads in flight by resource. The zero value is ready to use.
+type Group[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[T] // reads in flight by resource
+}
+
+// call is a read shared by concurrent lookups.
+type call[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Do returns the result of read for name, waiting for a read of name that is
+// already in flight instead of starting another one.
+func (g *Group[T]) Do(ctx context.Context, name string, read func() (T, error)) (T, error) {
+	g.mu.Lock()
+	if c, ok := g.calls[name]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.value, c.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+	c := &call[T]{done: make(chan struct{})}
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+	g.calls[name] = c
+	g.mu.Unlock()
+
+	c.value, c.err = read()
+
+	g.mu.Lock()
+	delete(g.calls, name)
+	g.mu.Unlock()
+	close(c.done)
+
+	return c.value, c.err
+}
@@ -0,0 +1,423 @@
+package yae
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Source identifies a configuration layer. Layers are applied in the order
+// below and each one only fills the fields it provides, so a later source
+// overrides an earlier one.
+type Source string
+
+const (
+	SourceDefault  Source = "default"  // default struct tags
+	SourceFile     Source = "file"     // the config file
+	SourceEnv      Source = "env"      // environment variables
+	SourceSecret   Source = "secret"   // the secret store
+	SourceOverride Source = "override" // Env.Overrides
+)
+
+// layers holds the state of a single layered load.
+type layers struct {
+	c          *Env
+	loader     *Loader
+	log        *slog.Logger
+	envType    EnvType
+	configFile string // config file that was read, if any
+	fields     []field
+	origins    Provenance      // field path to the origin of its current value
+	errs       []error         // errors collected across layers, joined at the end of the load
+	failed     map[string]bool // paths of the fields that already have an error
+}
+
+func (c *Env) newLayers(loader *Loader, t EnvType) *layers {
+	l := &layers{
+		c:       c,
+		loader:  loader,
+		log:     c.logger(),
+		envType: t,
+		fields:  c.fields(),
+		origins: make(Provenance),
+		failed:  make(map[string]bool),
+	}
+	c.provenance = l.origins
+	return l
+}
+
+/*
+load fills the config struct from every layer:
+
+	defaults < config file < environment variables < secret store < overrides
+
+The secret store is always used in dev and local, where missing required
+secrets are prompted for. In prod it is only used when Env.Store is set.
+Secrets are looked up concurrently and give up when ctx is done, the prompts
+that follow are not cancelled.
+
+The hooks of the config struct are called around the layers, see Defaulter.
+Once every layer is applied the fields are validated, see validate.
+
+A field that cannot be set does not stop the load: every *FieldError is
+collected, along with a *MissingEnvError for the required fields that no layer
+provided and a *ValidationError for every field that breaks its rules, and
+returned together with errors.Join.
+*/
+func (c *Env) load(ctx context.Context, loader *Loader, t EnvType) error {
+	l := c.newLayers(loader, t)
+
+	l.defaults()
+	l.setDefaults()
+	if err := l.file(); err != nil {
+		// the struct may be partially decoded, so stop here
+		l.errs = append(l.errs, err)
+		return l.err()
+	}
+	l.env()
+	if t != PROD || c.Store != nil {
+		l.applySecrets(l.secrets(ctx, t != PROD).ToMap())
+	}
+	l.overrides()
+	l.required()
+	l.normalize()
+	l.validate()
+	l.afterLoad()
+
+	return l.err()
+}
+
+// err returns the errors collected so far.
+func (l *layers) err() error {
+	return errors.Join(l.errs...)
+}
+
+// fail records an error for a field. The field keeps its previous value.
+func (l *layers) fail(f field, source Source, err error) {
+	l.errs = append(l.errs, &FieldError{Path: f.path, Source: source, Err: err})
+	l.failed[f.path] = true
+}
+
+// setField sets a field and records where the value came from.
+func (l *layers) setField(f field, origin Origin, value string) {
+	if err := f.set(value); err != nil {
+		l.fail(f, origin.Source, err)
+		return
+	}
+	l.record(f, origin)
+}
+
+// record stores the origin of the field's current value. Values are never
+// logged, only where they came from.
+func (l *layers) record(f field, origin Origin) {
+	origin.Value = f.display(origin.Source)
+	l.origins[f.path] = origin
+	l.log.Debug("field set", "field", f.path, "source", origin.Source, "from", origin.Name)
+}
+
+// defaults sets every field that has a default tag to its default value.
+func (l *layers) defaults() {
+	for _, f := range l.fields {
+		def, ok := f.defaultValue()
+		if !ok {
+			continue
+		}
+
+		if def == "" {
+			l.record(f, Origin{Source: SourceDefault})
+			continue
+		}
+
+		l.log.Debug("setting default", "field", f.path)
+		l.setField(f, Origin{Source: SourceDefault}, def)
+	}
+}
+
+// file loads the config file if one exists.
+func (l *layers) file() error {
+	confFile, ok := l.c.configFile()
+	if !ok {
+		l.log.Debug("config file not found, skipping", "file", l.c.Name, "path", l.c.Path)
+		return nil
+	}
+	l.log.Debug("loading config from file", "file", confFile)
+	l.configFile = confFile
+
+	data, err := os.ReadFile(confFile)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %s, error: %w", confFile, err)
+	}
+
+	var raw interface{}
+	switch strings.ToLower(string(l.c.Type)) {
+	case string(JSON):
+		if err = json.Unmarshal(data, l.c.ConfigStruct); err == nil {
+			err = json.Unmarshal(data, &raw)
+		}
+	case string(YAML):
+		if err = yaml.Unmarshal(data, l.c.ConfigStruct); err == nil {
+			err = yaml.Unmarshal(data, &raw)
+		}
+	case string(TOML):
+		if err = toml.Unmarshal(data, l.c.ConfigStruct); err == nil {
+			err = toml.Unmarshal(data, &raw)
+		}
+	case string(DOTENV):
+		vars, err := ParseDotEnv(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to parse file: %s, error: %w", confFile, err)
+		}
+		l.lookup(Origin{Source: SourceFile, Name: confFile}, func(name string) string { return vars[name] })
+		return nil
+	default:
+		return fmt.Errorf("unsupported file type: %s", l.c.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse file: %s, error: %w", confFile, err)
+	}
+
+	// the struct is already decoded, this only records which fields the file provided.
+	// yaml.v2 matches keys exactly, the JSON and TOML decoders ignore case.
+	foldCase := !strings.EqualFold(string(l.c.Type), string(YAML))
+	for _, f := range l.fields {
+		if hasPath(raw, f.names, foldCase) {
+			l.record(f, Origin{Source: SourceFile, Name: confFile})
+		}
+	}
+	return nil
+}
+
+// env loads every field whose environment variable is set.
+func (l *layers) env() {
+	l.log.Debug("loading config from env", "prefix", l.c.EnvPrefix)
+	l.lookup(Origin{Source: SourceEnv}, os.Getenv)
+}
+
+// lookup sets the fields for which lookup returns a value for the field's env name.
+// The env name is used as the origin name unless origin already has one.
+func (l *layers) lookup(origin Origin, lookup func(string) string) {
+	for _, f := range l.fields {
+		if contains(l.c.SkipFields, f.path) {
+			continue
+		}
+
+		l.log.Debug("loading env", "field", f.path, "env", f.env)
+		if value := lookup(f.env); value != "" {
+			o := origin
+			if o.Name == "" {
+				o.Name = f.env
+			}
+			l.setField(f, o, value)
+		}
+	}
+}
+
+// secrets fetches the keys of the struct from the secret store. When prompt is
+// set, required fields that no earlier layer provided are then prompted for one
+// at a time, unless the store is read-only. Secrets that are not found are skipped, any other store error,
+// including ctx being done, is recorded against its field.
+func (l *layers) secrets(ctx context.Context, prompt bool) *Secrets {
+	store, service := l.c.secretStore(), l.service()
+
+	var (
+		fields []field
+		keys   []string
+	)
+	for _, f := range l.fields {
+		if f.key == "" || contains(l.c.SkipFields, f.path) {
+			continue
+		}
+		fields = append(fields, f)
+		keys = append(keys, f.key)
+	}
+
+	l.log.Debug("looking up secrets", "service", service, "keys", len(keys))
+	values, errs := getSecrets(ctx, store, service, keys)
+
+	secrets := Secrets{}
+	for i, f := range fields {
+		value, err := values[i], errs[i]
+		if _, ok := l.origins[f.path]; notFound(err) && prompt && !ok && !l.failed[f.path] && !f.optional() && !readOnly(store) {
+			if err = setKey(store, service, f.key, !l.loader.stubPrompts); err == nil {
+				value, err = getSecret(ctx, store, service, f.key)
+			}
+		}
+		if err != nil {
+			if !notFound(err) {
+				l.fail(f, SourceSecret, err)
+			}
+			continue
+		}
+
+		secrets = append(secrets, Secret{Name: f.key, Value: value})
+	}
+	return &secrets
+}
+
+// applySecrets sets the fields whose key is in values.
+func (l *layers) applySecrets(values map[string]string) {
+	service := l.service()
+	for _, f := range l.fields {
+		if f.key == "" {
+			continue
+		}
+		if value, ok := values[f.key]; ok {
+			l.setField(f, Origin{Source: SourceSecret, Name: service + "/" + f.key}, value)
+		}
+	}
+}
+
+// service returns the secret store service of the config, falling back to the
+// config file name as earlier versions did.
+func (l *layers) service() string {
+	if l.c.Service != "" {
+		return l.c.Service
+	}
+	if l.c.Name != "" {
+		return l.c.Name
+	}
+	return svc
+}
+
+// overrides sets the fields in Env.Overrides, which are keyed by field path or key.
+// Overrides that do not match any field are reported as errors.
+func (l *layers) overrides() {
+	used := make(map[string]bool, len(l.c.Overrides))
+	for _, f := range l.fields {
+		name := f.path
+		value, ok := l.c.Overrides[name]
+		if !ok && f.key != "" {
+			name = f.key
+			value, ok = l.c.Overrides[name]
+		}
+		if !ok {
+			continue
+		}
+
+		used[name] = true
+		l.setField(f, Origin{Source: SourceOverride, Name: name}, value)
+	}
+
+	for name := range l.c.Overrides {
+		if !used[name] {
+			l.errs = append(l.errs, fmt.Errorf("override %s does not match any field", name))
+		}
+	}
+}
+
+// required records a MissingEnvError for the required fields that no layer
+// provided. Fields that failed to be set already have an error of their own.
+//
+// In dev and local a field without a key, which only the environment can set,
+// is not reported unless it is tagged yae:"required". Whether a config file
+// was read does not change which fields are required.
+func (l *layers) required() {
+	missing := &MissingEnvError{}
+	for _, f := range l.fields {
+		if _, ok := l.origins[f.path]; ok || l.failed[f.path] || f.optional() || contains(l.c.SkipFields, f.path) {
+			continue
+		}
+		if !hasOption(f.sf, "required") && l.envType != PROD && f.key == "" {
+			continue
+		}
+
+		l.log.Debug("required field not found", "field", f.path, "env", f.env)
+		l.failed[f.path] = true
+		missing.Names = append(missing.Names, f.env)
+		missing.Fields = append(missing.Fields, f.path)
+	}
+
+	if len(missing.Names) > 0 {
+		l.errs = append(l.errs, missing)
+	}
+}
+
+// validate checks the validate tag of every field that does not already have
+// an error. The Validate method of the config struct is only called when
+// nothing failed so far, as the struct may be incomplete otherwise.
+func (l *layers) validate() {
+	for _, f := range l.fields {
+		tag := f.sf.Tag.Get("validate")
+		if tag == "" || l.failed[f.path] {
+			continue
+		}
+
+		v, _ := f.value()
+		if rule, err := validateValue(v, tag); err != nil {
+			l.errs = append(l.errs, &ValidationError{Path: f.path, Rule: rule, Err: err})
+			l.failed[f.path] = true
+		}
+	}
+
+	if len(l.errs) > 0 {
+		return
+	}
+	if v, ok := l.c.ConfigStruct.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			l.errs = append(l.errs, &HookError{Hook: HookValidate, Err: err})
+		}
+	}
+}
+
+// configFile returns the config file to load, checking the name first and then the full path.
+func (c *Env) configFile() (string, bool) {
+	if c.Name == "" {
+		return "", false
+	}
+
+	f, fp := buildFilePath(c.Name, c.Path)
+	for _, name := range []string{f, fp} {
+		if info, err := os.Stat(name); err == nil && !info.IsDir() {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// hasPath reports whether the decoded file contains the nested key path. Keys
+// are matched case-insensitively when foldCase is set, to follow the decoder
+// of the file.
+func hasPath(raw interface{}, names []string, foldCase bool) bool {
+	match := func(k string, name string) bool {
+		if foldCase {
+			return strings.EqualFold(k, name)
+		}
+		return k == name
+	}
+
+	for _, name := range names {
+		var (
+			next  interface{}
+			found bool
+		)
+		switch m := raw.(type) {
+		case map[string]interface{}:
+			for k, v := range m {
+				if match(k, name) {
+					next, found = v, true
+					break
+				}
+			}
+		case map[interface{}]interface{}:
+			for k, v := range m {
+				if ks, ok := k.(string); ok && match(ks, name) {
+					next, found = v, true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+		raw = next
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package yae
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+Loader loads configs into Envs. A Loader holds all the state of a load and
+nothing is shared through package-level variables, so one Loader can be used
+from many goroutines, e.g. to load per-tenant configs, as long as each call is
+given its own Env and config struct.
+
+The options passed to New configure the Env used by Load:
+
+	loader := yae.New(
+		yae.WithFile("config.yaml", yae.YAML),
+		yae.WithEnvPrefix("APP"),
+		yae.WithKeyringService("my-service"),
+	)
+	err := loader.Load(yae.DEV, &cfg)
+
+Get, GetContext, LoadConfig and BuildDevEnv use the options for the fields
+of the given Env that are not set. The package-level Get, LoadConfig and
+BuildDevEnv use a new Loader for every call.
+*/
+type Loader struct {
+	env         Env  // copied by Env for every load
+	stubPrompts bool // answer prompts with the key name instead of reading stdin, for tests
+}
+
+// New returns a Loader with the options applied.
+func New(opts ...Option) *Loader {
+	l := &Loader{}
+	for _, opt := range opts {
+		opt(&l.env)
+	}
+	return l
+}
+
+// Env returns a new Env configured with the Loader's options that loads into dst.
+func (l *Loader) Env(dst interface{}) *Env {
+	c := l.env
+	c.ConfigStruct = dst
+	return &c
+}
+
+// fill sets the fields of c that are not set to the Loader's options.
+func (l *Loader) fill(c *Env) {
+	o := &l.env
+	if c.Name == "" {
+		c.Name = o.Name
+	}
+	if c.Service == "" {
+		c.Service = o.Service
+	}
+	if !c.Debug {
+		c.Debug = o.Debug
+	}
+	if c.Type == "" {
+		c.Type = o.Type
+	}
+	if c.Path == "" {
+		c.Path = o.Path
+	}
+	if c.EnvPrefix == "" {
+		c.EnvPrefix = o.EnvPrefix
+	}
+	if c.SkipFields == nil {
+		c.SkipFields = o.SkipFields
+	}
+	if c.Store == nil {
+		c.Store = o.Store
+	}
+	if c.Overrides == nil {
+		c.Overrides = o.Overrides
+	}
+	if c.Logger == nil {
+		c.Logger = o.Logger
+	}
+}
+
+// Load loads the config into dst, which must be a pointer to a struct, using
+// the Loader's options. Use Env and Get to inspect the Provenance of the load.
+func (l *Loader) Load(t EnvType, dst interface{}) error {
+	return l.Get(t, l.Env(dst))
+}
+
+// Get retrieves the configuration based on the specified environment type.
+// Values are layered as described in load, so each source only overrides the
+// fields it provides.
+func (l *Loader) Get(t EnvType, c *Env) error {
+	return l.GetContext(context.Background(), t, c)
+}
+
+// GetContext is like Get but stops waiting on the secret store once ctx is
+// done. Every secret that could not be looked up is reported in a FieldError
+// wrapping ctx.Err().
+func (l *Loader) GetContext(ctx context.Context, t EnvType, c *Env) error {
+	l.fill(c)
+	log := c.logger()
+
+	switch t {
+	case DEV, LOCAL:
+		log.Debug("loading config with the keychain")
+	case PROD:
+		log.Debug("loading config from file and env", "file", c.Name, "path", c.Path)
+	default:
+		return fmt.Errorf("unsupported environment type: %s", t)
+	}
+
+	if err := c.checkConfigStruct(); err != nil {
+		return err
+	}
+	return c.load(ctx, l, t)
+}
+
+// LoadConfig loads the config from the file with environment variables layered on top.
+func (l *Loader) LoadConfig(c *Env) error {
+	l.fill(c)
+	if err := c.checkConfigStruct(); err != nil {
+		return err
+	}
+	return c.load(context.Background(), l, PROD)
+}
+
+// BuildDevEnv fills the values of the struct with the values from the secret store.
+// Optional fields and fields with a default are not prompted for when they are missing
+// from the store.
+func (l *Loader) BuildDevEnv(c *Env, secrets *Secrets, skipFields ...string) error {
+	l.fill(c)
+	if err := c.checkConfigStruct(); err != nil {
+		return err
+	}
+
+	ls := c.newLayers(l, DEV)
+	ls.defaults()
+	ls.setDefaults()
+
+	if secrets == nil {
+		secrets = ls.secrets(context.Background(), true)
+	}
+	ls.applySecrets(secrets.ToMap(skipFields...))
+	ls.normalize()
+	ls.validate()
+	ls.afterLoad()
+	return ls.err()
+}
+
+/*
+Load loads a config of type T with the options applied and returns it.
+
+	cfg, err := yae.Load[Config](yae.PROD, yae.WithFile("config.yaml", yae.YAML), yae.WithEnvPrefix("APP"))
+
+Go has no constraint for struct types, so T is checked when Load is called and
+a T that is not a struct returns ErrInvalidConfigStruct. On any other error the
+returned config holds the values that could be loaded.
+*/
+func Load[T any](t EnvType, opts ...Option) (T, error) {
+	var cfg T
+	err := New(opts...).Load(t, &cfg)
+	return cfg, err
+}
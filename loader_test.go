@@ -0,0 +1,113 @@
+package yae
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoaderConcurrent tests that one Loader can load per-tenant configs from many goroutines
+func TestLoaderConcurrent(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Token  string `json:"token"`
+		Region string `json:"region" default:"us-east-1"`
+	}
+
+	const tenants = 20
+
+	var (
+		wg   sync.WaitGroup
+		cfgs [tenants]Conf
+		errs [tenants]error
+		envs [tenants]*Env
+	)
+	for i := 0; i < tenants; i++ {
+		name := fmt.Sprintf("tenant-%d", i)
+		envs[i] = &Env{Name: name, Type: JSON, ConfigStruct: &cfgs[i], Store: mapStore{name: {"api_key": name}}}
+	}
+
+	for i := 0; i < tenants; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = stubLoader.Get(DEV, envs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < tenants; i++ {
+		assert.NoError(t, errs[i])
+		assert.Equal(t, fmt.Sprintf("tenant-%d", i), cfgs[i].APIKey)
+		// the stub prompt answers with the key name
+		assert.Equal(t, "token", cfgs[i].Token)
+		assert.Equal(t, "us-east-1", cfgs[i].Region)
+		assert.Equal(t, SourceSecret, envs[i].Provenance()["APIKey"].Source)
+	}
+}
+
+// TestLoaderOptions tests that the keyring service is separate from the config file name
+func TestLoaderOptions(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Region string `json:"region"`
+	}
+
+	loader := New(
+		WithFile("missing.json", JSON),
+		WithEnvPrefix("SVC"),
+		WithKeyringService("svc"),
+		WithSecretStore(mapStore{"svc": {"api_key": "abc123"}, "missing.json": {"api_key": "wrong"}}),
+		WithOverrides(map[string]string{"region": "eu-west-1"}),
+	)
+
+	var cfg Conf
+	c := loader.Env(&cfg)
+	err := loader.Get(PROD, c)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+	assert.Equal(t, "eu-west-1", cfg.Region)
+	assert.Equal(t, Origin{Source: SourceSecret, Name: "svc/api_key", Value: redacted}, c.Provenance()["APIKey"])
+
+	// every load gets its own Env
+	var other Conf
+	assert.NoError(t, loader.Load(PROD, &other))
+	assert.Equal(t, cfg, other)
+	assert.Nil(t, loader.env.ConfigStruct)
+}
+
+// TestLoaderGetOptions tests that Get uses the options for the fields the Env does not set
+func TestLoaderGetOptions(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Region string `json:"region" yae:"optional"`
+	}
+
+	var buf bytes.Buffer
+	loader := New(
+		WithKeyringService("svc"),
+		WithSecretStore(mapStore{"svc": {"api_key": "abc123", "region": "us-east-1"}}),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+
+	var cfg Conf
+	err := loader.Get(PROD, &Env{Name: "missing.json", Type: JSON, ConfigStruct: &cfg})
+	assert.NoError(t, err)
+	assert.Equal(t, Conf{APIKey: "abc123", Region: "us-east-1"}, cfg)
+	assert.Contains(t, buf.String(), "looking up secrets")
+
+	// fields set on the Env win over the options
+	var other Conf
+	err = loader.Get(PROD, &Env{
+		Name:         "missing.json",
+		Type:         JSON,
+		ConfigStruct: &other,
+		Store:        mapStore{"svc": {"api_key": "from-env-store"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Conf{APIKey: "from-env-store"}, other)
+}
@@ -0,0 +1,77 @@
+package yae
+
+import "log/slog"
+
+// Option configures the Env of a Loader, see New and Load.
+type Option func(*Env)
+
+// WithFile loads the config file name of the given type. name is looked up as
+// is and then relative to the path set with WithPath.
+func WithFile(name string, configType ConfigType) Option {
+	return func(c *Env) {
+		c.Name = name
+		c.Type = configType
+	}
+}
+
+// WithPath sets the directory the config file is looked up in.
+func WithPath(path string) Option {
+	return func(c *Env) {
+		c.Path = path
+	}
+}
+
+// WithEnvPrefix sets the prefix of the environment variable names.
+func WithEnvPrefix(prefix string) Option {
+	return func(c *Env) {
+		c.EnvPrefix = prefix
+	}
+}
+
+// WithSecretStore sets the secret store used instead of the system keyring.
+func WithSecretStore(store SecretStore) Option {
+	return func(c *Env) {
+		c.Store = store
+	}
+}
+
+// WithKeyringService sets the service secrets are stored under in the secret
+// store. It defaults to the config file name.
+func WithKeyringService(service string) Option {
+	return func(c *Env) {
+		c.Service = service
+	}
+}
+
+// WithLogger sets the logger for debug messages.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Env) {
+		c.Logger = logger
+	}
+}
+
+// WithDebug logs debug messages as JSON to stdout, unless a logger is set.
+func WithDebug(debug bool) Option {
+	return func(c *Env) {
+		c.Debug = debug
+	}
+}
+
+// WithSkipFields skips the fields, matched against their path, when loading from env and the secret store.
+func WithSkipFields(fields ...string) Option {
+	return func(c *Env) {
+		c.SkipFields = append(c.SkipFields, fields...)
+	}
+}
+
+// WithOverrides sets values that take precedence over every source, keyed by field path or key.
+func WithOverrides(overrides map[string]string) Option {
+	return func(c *Env) {
+		if c.Overrides == nil {
+			c.Overrides = make(map[string]string, len(overrides))
+		}
+		for k, v := range overrides {
+			c.Overrides[k] = v
+		}
+	}
+}
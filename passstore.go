@@ -0,0 +1,235 @@
+package yae
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultPassPrefix is the directory of the password store yae keeps its entries in unless PassStore.Prefix is set.
+const defaultPassPrefix = "yae"
+
+/*
+PassStore is a SecretStore that reads and writes entries of a password-store
+(pass), by running gpg.
+
+The entry of a key is <Dir>/<Prefix>/<service>/<key>.gpg, so it can also be
+read with pass show yae/myapp/api_key. Dir defaults to PASSWORD_STORE_DIR or
+~/.password-store, and entries are encrypted for the recipients in the nearest
+.gpg-id file, like pass does. gpg uses the keys and agent of GNUPGHOME.
+
+Like pass show, the first line of an entry is the secret and the lines after
+it, such as a username or URL, are ignored.
+
+gopass stores can be used by setting Dir to the root of a store that uses gpg,
+e.g. ~/.local/share/gopass/stores/root. The gopass config is not read to find
+it, and stores encrypted with age are not supported.
+*/
+type PassStore struct {
+	Dir    string // Root of the password store
+	Prefix string // Directory under Dir holding the entries, defaults to yae
+	GPG    string // gpg binary, defaults to gpg
+}
+
+// Get decrypts the entry of key for service and returns its first line.
+func (p *PassStore) Get(service, key string) (string, error) {
+	return p.GetContext(context.Background(), service, key)
+}
+
+// GetContext decrypts the entry of key for service and returns its first line,
+// killing gpg when ctx is done.
+func (p *PassStore) GetContext(ctx context.Context, service, key string) (string, error) {
+	file, err := p.entry(service, key)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return "", ErrSecretNotFound
+	}
+
+	out, err := p.gpg(ctx, nil, "--decrypt", file)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", file, err)
+	}
+	secret, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSuffix(secret, "\r"), nil
+}
+
+// Set encrypts value into the entry of key for service, replacing it if it exists.
+func (p *PassStore) Set(service, key, value string) error {
+	file, err := p.entry(service, key)
+	if err != nil {
+		return err
+	}
+	recipients, err := p.recipients(filepath.Dir(file))
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--encrypt", "--no-encrypt-to"}
+	for _, r := range recipients {
+		args = append(args, "--recipient", r)
+	}
+	out, err := p.gpg(context.Background(), strings.NewReader(value+"\n"), args...)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", file, err)
+	}
+	return writeFileAtomic(file, out)
+}
+
+// Delete removes the entry of key for service, and the service directory once it is empty.
+func (p *PassStore) Delete(service, key string) error {
+	file, err := p.entry(service, key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrSecretNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", file, err)
+	}
+
+	// like pass rm, clean up the directories the entry leaves empty
+	root := p.root()
+	for dir := filepath.Dir(file); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// List returns the sorted keys of the entries for service.
+func (p *PassStore) List(service string) ([]string, error) {
+	dir, err := p.entry(service, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) && path == dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".gpg") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, strings.TrimSuffix(path, ".gpg"))
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// root returns the directory of the password store.
+func (p *PassStore) root() string {
+	if p.Dir != "" {
+		return filepath.Clean(p.Dir)
+	}
+	if dir := os.Getenv("PASSWORD_STORE_DIR"); dir != "" {
+		return filepath.Clean(dir)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".password-store"
+	}
+	return filepath.Join(home, ".password-store")
+}
+
+// entry returns the file of key for service, or the service directory if key is
+// empty. Keys must stay inside the prefix directory.
+func (p *PassStore) entry(service, key string) (string, error) {
+	prefix := p.Prefix
+	if prefix == "" {
+		prefix = defaultPassPrefix
+	}
+	base := filepath.Join(p.root(), prefix)
+
+	path := filepath.Join(base, service, key)
+	if key != "" {
+		path += ".gpg"
+	}
+	if path != base && !strings.HasPrefix(path, base+string(filepath.Separator)) {
+		return "", fmt.Errorf("password store entry %s/%s is outside of %s", service, key, base)
+	}
+	return path, nil
+}
+
+// recipients reads the .gpg-id file nearest to dir, up to the root of the store.
+func (p *PassStore) recipients(dir string) ([]string, error) {
+	root := p.root()
+	for {
+		b, err := os.ReadFile(filepath.Join(dir, ".gpg-id"))
+		if err == nil {
+			var ids []string
+			for _, line := range strings.Split(string(b), "\n") {
+				// .gpg-id files may contain comments
+				line, _, _ = strings.Cut(line, "#")
+				if line = strings.TrimSpace(line); line != "" {
+					ids = append(ids, line)
+				}
+			}
+			if len(ids) == 0 {
+				return nil, fmt.Errorf("%s has no recipients", filepath.Join(dir, ".gpg-id"))
+			}
+			return ids, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read .gpg-id: %w", err)
+		}
+		if dir == root || !strings.HasPrefix(dir, root) {
+			return nil, fmt.Errorf("no .gpg-id found in %s, initialize it with pass init", root)
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// gpg runs gpg non-interactively with args and returns its output.
+func (p *PassStore) gpg(ctx context.Context, stdin io.Reader, args ...string) ([]byte, error) {
+	bin := p.GPG
+	if bin == "" {
+		bin = "gpg"
+	}
+
+	args = append([]string{"--quiet", "--yes", "--batch", "--compress-algo=none"}, args...)
+	cmd := exec.CommandContext(ctx, bin, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,119 @@
+package yae_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/johnmikee/yae"
+	"github.com/stretchr/testify/assert"
+)
+
+// newPassStore creates a password store with a .gpg-id for a new key in a
+// throwaway GNUPGHOME.
+func newPassStore(t *testing.T) string {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+
+	home := t.TempDir()
+	t.Setenv("GNUPGHOME", home)
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+	})
+
+	out, err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key",
+		"yae test <yae@example.com>", "default", "default", "never").CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to generate key: %s: %s", err, out)
+	}
+
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".gpg-id"), []byte("yae@example.com\n"), 0o600))
+	return dir
+}
+
+func TestPassStore(t *testing.T) {
+	dir := newPassStore(t)
+	store := &yae.PassStore{Dir: dir}
+
+	_, err := store.Get("myapp", "api_key")
+	assert.ErrorIs(t, err, yae.ErrSecretNotFound)
+
+	assert.NoError(t, store.Set("myapp", "api_key", "abc123"))
+	assert.NoError(t, store.Set("myapp", "database/password", "hunter2"))
+
+	value, err := store.Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+
+	// entries are plain pass entries, readable with gpg
+	out, err := exec.Command("gpg", "--quiet", "--batch", "--decrypt", filepath.Join(dir, "yae", "myapp", "api_key.gpg")).Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123\n", string(out))
+
+	// like pass show, only the first line is the secret
+	cmd := exec.Command("gpg", "--quiet", "--batch", "--encrypt", "--recipient", "yae@example.com",
+		"--output", filepath.Join(dir, "yae", "myapp", "login.gpg"))
+	cmd.Stdin = strings.NewReader("s3cret\r\nusername: admin\nurl: https://example.com\n")
+	out, err = cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+	value, err = store.Get("myapp", "login")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+
+	keys, err := store.List("myapp")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "database/password", "login"}, keys)
+
+	keys, err = store.List("other")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	assert.NoError(t, store.Delete("myapp", "database/password"))
+	assert.ErrorIs(t, store.Delete("myapp", "database/password"), yae.ErrSecretNotFound)
+	assert.NoDirExists(t, filepath.Join(dir, "yae", "myapp", "database"))
+	assert.DirExists(t, filepath.Join(dir, "yae", "myapp"))
+
+	_, err = store.Get("myapp", "../../escape")
+	assert.ErrorContains(t, err, "outside of")
+}
+
+func TestPassStoreRecipients(t *testing.T) {
+	dir := newPassStore(t)
+	assert.NoError(t, os.Remove(filepath.Join(dir, ".gpg-id")))
+
+	store := &yae.PassStore{Dir: dir, Prefix: "team"}
+	assert.ErrorContains(t, store.Set("myapp", "api_key", "abc123"), "no .gpg-id found")
+
+	// the nearest .gpg-id is used, like pass init -p
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "team"), 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "team", ".gpg-id"), []byte("yae@example.com\n"), 0o600))
+	assert.NoError(t, store.Set("myapp", "api_key", "abc123"))
+
+	t.Setenv("PASSWORD_STORE_DIR", dir)
+	value, err := (&yae.PassStore{Prefix: "team"}).Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+}
+
+func TestPassStoreLoad(t *testing.T) {
+	dir := newPassStore(t)
+	store := &yae.PassStore{Dir: dir}
+	assert.NoError(t, store.Set("myapp", "api_key", "abc123"))
+
+	type Conf struct {
+		APIKey string `json:"api_key"`
+	}
+
+	var conf Conf
+	err := yae.New(
+		yae.WithFile("myapp.json", yae.JSON),
+		yae.WithKeyringService("myapp"),
+		yae.WithSecretStore(store),
+	).Load(yae.PROD, &conf)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", conf.APIKey)
+}
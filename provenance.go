@@ -0,0 +1,77 @@
+package yae
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"text/tabwriter"
+)
+
+// redacted replaces values that must not be shown.
+const redacted = "[redacted]"
+
+// Origin describes where the value of a field came from.
+type Origin struct {
+	Source Source // the layer that set the value
+	Name   string // the file, env variable, service/key or override key that provided it
+	Value  string // the value, redacted unless the field is tagged yae:"public"
+}
+
+// Provenance maps field paths, e.g. Database.Host, to the origin of their value.
+// Fields that no source provided are not included.
+type Provenance map[string]Origin
+
+// Provenance returns the origin of every field set by the last load of c.
+func (c *Env) Provenance() Provenance {
+	return c.provenance
+}
+
+// Table writes the provenance as a table sorted by field path. Values are
+// redacted unless the field is tagged yae:"public".
+func (p Provenance) Table(w io.Writer) error {
+	paths := make([]string, 0, len(p))
+	for path := range p {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tSOURCE\tFROM\tVALUE")
+	for _, path := range paths {
+		o := p[path]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", path, o.Source, o.Name, o.Value)
+	}
+	return tw.Flush()
+}
+
+// display formats the current value of the field for the provenance report.
+// Only fields tagged yae:"public" are shown, and never when the value came
+// from the secret store or the field is also tagged yae:"secret".
+func (f field) display(source Source) string {
+	if !f.public() || source == SourceSecret || f.secret() {
+		return redacted
+	}
+
+	v, ok := f.value()
+	if !ok {
+		return ""
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		v = v.Elem()
+	}
+	return formatValue(v)
+}
+
+// formatValue formats v, using its String method if its pointer has one.
+func formatValue(v reflect.Value) string {
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,104 @@
+package yae
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type provenanceConfig struct {
+	Host     string        `json:"host" yae:"public"`
+	Port     int           `json:"port" default:"8080" yae:"public"`
+	Timeout  time.Duration `json:"timeout" default:"5s" yae:"public"`
+	Password string        `json:"password" yae:"secret"`
+	APIKey   string        `json:"api_key"`
+	Region   string        `json:"region"`
+	Unset    string        `json:"unset" yae:"optional"`
+}
+
+func TestProvenance(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(file, []byte(`{"host": "file-host", "port": 9090, "password": "hunter2"}`), 0o644); err != nil {
+		t.Fatalf("failed to write test config file: %v", err)
+	}
+	t.Setenv("APP_PORT", "9191")
+
+	var cfg provenanceConfig
+	c := &Env{
+		Name:         "config.json",
+		Path:         dir,
+		Type:         JSON,
+		EnvPrefix:    "APP",
+		ConfigStruct: &cfg,
+		Store:        mapStore{"config.json": {"api_key": "abc123"}},
+		Overrides:    map[string]string{"region": "eu-west-1"},
+	}
+	err := Get(PROD, c)
+	assert.NoError(t, err)
+
+	assert.Equal(t, Provenance{
+		"Host":     {Source: SourceFile, Name: file, Value: "file-host"},
+		"Port":     {Source: SourceEnv, Name: "APP_PORT", Value: "9191"},
+		"Timeout":  {Source: SourceDefault, Value: "5s"},
+		"Password": {Source: SourceFile, Name: file, Value: redacted},
+		"APIKey":   {Source: SourceSecret, Name: "config.json/api_key", Value: redacted},
+		"Region":   {Source: SourceOverride, Name: "region", Value: redacted},
+	}, c.Provenance())
+
+	var sb strings.Builder
+	assert.NoError(t, c.Provenance().Table(&sb))
+
+	table := sb.String()
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	assert.Len(t, lines, 7)
+	assert.Equal(t, []string{"FIELD", "SOURCE", "FROM", "VALUE"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"Port", "env", "APP_PORT", "9191"}, strings.Fields(lines[4]))
+	assert.NotContains(t, table, "hunter2")
+	assert.NotContains(t, table, "abc123")
+	// values are only shown for public fields
+	assert.NotContains(t, table, "eu-west-1")
+}
+
+func TestProvenanceDevEnv(t *testing.T) {
+	var cfg struct {
+		APIKey string `json:"api_key"`
+	}
+
+	c := &Env{Type: JSON, ConfigStruct: &cfg}
+	err := BuildDevEnv(c, &Secrets{{Name: "api_key", Value: "abc123"}})
+	assert.NoError(t, err)
+	assert.Equal(t, Provenance{
+		"APIKey": {Source: SourceSecret, Name: svc + "/api_key", Value: redacted},
+	}, c.Provenance())
+}
+
+// TestProvenanceYAMLKeys tests that only the keys yaml.v2 decodes, which are case-sensitive, count as set by the file
+func TestProvenanceYAMLKeys(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, []byte("API_KEY: wrong-case\nregion: eu-west-1\n"), 0o644); err != nil {
+		t.Fatalf("failed to write test config file: %v", err)
+	}
+
+	var cfg struct {
+		APIKey string `yaml:"api_key" yae:"required"`
+		Region string
+	}
+	c := &Env{Name: "config.yaml", Path: dir, Type: YAML, ConfigStruct: &cfg}
+	err := Get(PROD, c)
+
+	var missing *MissingEnvError
+	if assert.ErrorAs(t, err, &missing) {
+		assert.Equal(t, []string{"APIKey"}, missing.Fields)
+	}
+	assert.Equal(t, "", cfg.APIKey)
+	assert.Equal(t, "eu-west-1", cfg.Region)
+	assert.Equal(t, Provenance{
+		"Region": {Source: SourceFile, Name: file, Value: redacted},
+	}, c.Provenance())
+}
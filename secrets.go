@@ -1,13 +1,11 @@
 package yae
 
-import "github.com/zalando/go-keyring"
-
 // RemoveKey removes a key from the keyring.
 func RemoveKey(service, key string) error {
-	return keyring.Delete(key, service)
+	return KeyringStore{}.Delete(service, key)
 }
 
 // UpdateKey updates the value of a key in the keyring.
 func UpdateKey(service, key, value string) error {
-	return keyring.Set(key, value, service)
+	return KeyringStore{}.Set(service, key, value)
 }
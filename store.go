@@ -0,0 +1,155 @@
+package yae
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+// secretWorkers is the number of secrets looked up concurrently during a load.
+const secretWorkers = 8
+
+// ErrSecretNotFound is returned by a SecretStore when the requested secret does not exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// ErrReadOnlyStore is returned by Set and Delete of a SecretStore that cannot be written to.
+var ErrReadOnlyStore = errors.New("secret store is read-only")
+
+// SecretStore is a backend capable of storing and retrieving secrets.
+//
+// Implementations must return ErrSecretNotFound (or an error wrapping it) from Get
+// when the secret does not exist so yae knows to prompt for the value.
+type SecretStore interface {
+	// Get returns the value of key for service.
+	Get(service, key string) (string, error)
+	// Set stores value under key for service.
+	Set(service, key, value string) error
+	// Delete removes key for service.
+	Delete(service, key string) error
+	// List returns the keys stored for service.
+	List(service string) ([]string, error)
+}
+
+// ContextSecretStore is a SecretStore whose lookups can be cancelled. Stores
+// that talk to a remote backend should implement it so a load honors the
+// deadline of its context.
+type ContextSecretStore interface {
+	SecretStore
+	// GetContext returns the value of key for service, giving up when ctx is done.
+	GetContext(ctx context.Context, service, key string) (string, error)
+}
+
+// ReadOnlySecretStore is a SecretStore that may not accept writes, e.g. one
+// backed by files mounted by the platform. Missing secrets are not prompted for
+// when ReadOnly reports true, since storing the answer would fail.
+type ReadOnlySecretStore interface {
+	SecretStore
+	// ReadOnly reports whether Set and Delete return ErrReadOnlyStore.
+	ReadOnly() bool
+}
+
+// readOnly reports whether store is a ReadOnlySecretStore that is read-only.
+func readOnly(store SecretStore) bool {
+	ro, ok := store.(ReadOnlySecretStore)
+	return ok && ro.ReadOnly()
+}
+
+// KeyringStore is the default SecretStore backed by the system keyring.
+//
+// Entries are addressed as (key, service) in the keyring so secrets stored by
+// earlier versions of yae keep resolving.
+type KeyringStore struct{}
+
+// Get returns the value of key for service from the keyring.
+func (KeyringStore) Get(service, key string) (string, error) {
+	secret, err := keyring.Get(key, service)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrSecretNotFound
+	}
+	return secret, err
+}
+
+// Set stores value under key for service in the keyring.
+func (KeyringStore) Set(service, key, value string) error {
+	return keyring.Set(key, service, value)
+}
+
+// Delete removes key for service from the keyring.
+func (KeyringStore) Delete(service, key string) error {
+	err := keyring.Delete(key, service)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrSecretNotFound
+	}
+	return err
+}
+
+// List is not supported by the system keyring.
+func (KeyringStore) List(service string) ([]string, error) {
+	return nil, errors.New("listing secrets is not supported by the keyring")
+}
+
+// secretStore returns the store configured on the Env or the keyring.
+func (c *Env) secretStore() SecretStore {
+	if c.Store != nil {
+		return c.Store
+	}
+	return KeyringStore{}
+}
+
+// getSecret looks up key with the store's GetContext if it has one. Other
+// stores are called in a goroutine that is abandoned when ctx is done, since a
+// keyring call over D-Bus cannot be interrupted.
+func getSecret(ctx context.Context, store SecretStore, service, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if cs, ok := store.(ContextSecretStore); ok {
+		return cs.GetContext(ctx, service, key)
+	}
+
+	type result struct {
+		value string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := store.Get(service, key)
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// getSecrets looks up keys with a pool of secretWorkers goroutines. The value
+// and error of each key are returned at the key's index.
+func getSecrets(ctx context.Context, store SecretStore, service string, keys []string) ([]string, []error) {
+	values := make([]string, len(keys))
+	errs := make([]error, len(keys))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < secretWorkers && w < len(keys); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				values[i], errs[i] = getSecret(ctx, store, service, keys[i])
+			}
+		}()
+	}
+
+	for i := range keys {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return values, errs
+}
@@ -0,0 +1,400 @@
+package yae
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapStore is an in-memory SecretStore used in tests.
+type mapStore map[string]map[string]string
+
+func (m mapStore) Get(service, key string) (string, error) {
+	v, ok := m[service][key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return v, nil
+}
+
+func (m mapStore) Set(service, key, value string) error {
+	if m[service] == nil {
+		m[service] = map[string]string{}
+	}
+	m[service][key] = value
+	return nil
+}
+
+func (m mapStore) Delete(service, key string) error {
+	if _, ok := m[service][key]; !ok {
+		return ErrSecretNotFound
+	}
+	delete(m[service], key)
+	return nil
+}
+
+func (m mapStore) List(service string) ([]string, error) {
+	var keys []string
+	for k := range m[service] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// stubLoader answers prompts with the key name.
+var stubLoader = &Loader{stubPrompts: true}
+
+// failingStore is a SecretStore whose lookups fail with err.
+type failingStore struct {
+	mapStore
+	err error
+}
+
+func (f failingStore) Get(service, key string) (string, error) {
+	return "", f.err
+}
+
+// TestCheckKeyStore tests checkKey against a custom store
+func TestCheckKeyStore(t *testing.T) {
+	store := mapStore{"svc": {"found": "value"}}
+
+	secret, ok := checkKey(store, "svc", "found")
+	assert.True(t, ok)
+	assert.Equal(t, "value", secret)
+
+	secret, ok = checkKey(store, "svc", "missing")
+	assert.False(t, ok)
+	assert.Equal(t, "not-found", secret)
+}
+
+// TestGetStoreConfig tests that missing secrets are prompted for and saved to the store
+func TestGetStoreConfig(t *testing.T) {
+	store := mapStore{"svc": {"key1": "value1"}}
+
+	secrets := getStoreConfig(store, "svc", false, "key1", "key2")
+	assert.Equal(t, map[string]string{"key1": "value1", "key2": "key2"}, secrets.ToMap())
+
+	keys, err := store.List("svc")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key1", "key2"}, keys)
+}
+
+// TestBuildDevEnvStore tests that the Env store is used instead of the keyring
+func TestBuildDevEnvStore(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Port   int    `json:"port"`
+	}
+
+	var cfg Conf
+	err := Get(DEV, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        mapStore{"svc": {"api_key": "abc123", "port": "8080"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+	assert.Equal(t, 8080, cfg.Port)
+}
+
+// TestBuildDevEnvDefaults tests that fields with defaults are not prompted for
+func TestBuildDevEnvDefaults(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Region string `json:"region" default:"us-east-1"`
+		Port   int    `json:"port" default:"8080"`
+	}
+
+	store := mapStore{"svc": {"api_key": "abc123", "port": "9090"}}
+
+	var cfg Conf
+	err := stubLoader.Get(LOCAL, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        store,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+	assert.Equal(t, "us-east-1", cfg.Region)
+	assert.Equal(t, 9090, cfg.Port)
+
+	// the missing default was not prompted for and stored
+	keys, err := store.List("svc")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "port"}, keys)
+}
+
+// TestBuildDevEnvOptional tests that optional fields are not prompted for
+func TestBuildDevEnvOptional(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key" yae:"required"`
+		Region string `json:"region" yae:"optional"`
+	}
+
+	store := mapStore{}
+
+	var cfg Conf
+	err := stubLoader.Get(DEV, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        store,
+	})
+	assert.NoError(t, err)
+	// the stub prompt answers with the key name
+	assert.Equal(t, "api_key", cfg.APIKey)
+	assert.Equal(t, "", cfg.Region)
+
+	keys, err := store.List("svc")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api_key"}, keys)
+}
+
+// TestBuildDevEnvNoKey tests that dev does not require fields without a key, which only env can set
+func TestBuildDevEnvNoKey(t *testing.T) {
+	type Conf struct {
+		APIKey  string `json:"api_key"`
+		Verbose bool
+	}
+
+	var cfg Conf
+	err := stubLoader.Get(DEV, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        mapStore{"svc": {"api_key": "abc123"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+	assert.False(t, cfg.Verbose)
+}
+
+// TestLayeredSecrets tests that the secret store overrides env and only prompts for what is still missing
+func TestLayeredSecrets(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Region string `json:"region"`
+		Token  string `json:"token"`
+	}
+
+	t.Setenv("SVC_API_KEY", "from-env")
+	t.Setenv("SVC_REGION", "from-env")
+
+	store := mapStore{"svc": {"api_key": "from-store"}}
+
+	var cfg Conf
+	err := stubLoader.Get(DEV, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		EnvPrefix:    "SVC",
+		ConfigStruct: &cfg,
+		Store:        store,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "from-store", cfg.APIKey)
+	assert.Equal(t, "from-env", cfg.Region)
+	// the stub prompt answers with the key name
+	assert.Equal(t, "token", cfg.Token)
+
+	keys, err := store.List("svc")
+	assert.NoError(t, err)
+	// region came from env so it was not prompted for
+	assert.Equal(t, []string{"api_key", "token"}, keys)
+}
+
+// TestProdSecretStore tests that prod only uses the secret store when one is set, without prompting
+func TestProdSecretStore(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Region string `json:"region" yae:"optional"`
+	}
+
+	t.Setenv("API_KEY", "from-env")
+
+	var cfg Conf
+	err := Get(PROD, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        mapStore{"svc": {"region": "from-store"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", cfg.APIKey)
+	assert.Equal(t, "from-store", cfg.Region)
+}
+
+// TestSecretStoreErrors tests that store failures are reported per field while missing secrets are not
+func TestSecretStoreErrors(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Region string `json:"region" yae:"optional"`
+	}
+
+	unavailable := errors.New("store unavailable")
+
+	var cfg Conf
+	err := Get(PROD, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        failingStore{err: unavailable},
+	})
+	assert.ErrorIs(t, err, unavailable)
+
+	var fieldErr *FieldError
+	if assert.ErrorAs(t, err, &fieldErr) {
+		assert.Equal(t, "APIKey", fieldErr.Path)
+		assert.Equal(t, SourceSecret, fieldErr.Source)
+	}
+
+	err = Get(PROD, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        failingStore{err: ErrSecretNotFound},
+	})
+	assert.False(t, errors.As(err, &fieldErr))
+
+	var missing *MissingEnvError
+	assert.ErrorAs(t, err, &missing)
+}
+
+// slowStore is a SecretStore whose lookups take delay. It records the highest
+// number of lookups running at the same time.
+type slowStore struct {
+	mapStore
+	delay time.Duration
+
+	mu          sync.Mutex
+	active, max int
+}
+
+func (s *slowStore) Get(service, key string) (string, error) {
+	s.mu.Lock()
+	s.active++
+	if s.active > s.max {
+		s.max = s.active
+	}
+	s.mu.Unlock()
+
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	return s.mapStore.Get(service, key)
+}
+
+// hangingStore is a SecretStore whose lookups block until hang is closed.
+type hangingStore struct {
+	mapStore
+	hang chan struct{}
+}
+
+func (h hangingStore) Get(service, key string) (string, error) {
+	<-h.hang
+	return "", ErrSecretNotFound
+}
+
+// contextStore is a ContextSecretStore whose Get must not be called.
+type contextStore struct {
+	mapStore
+}
+
+func (c contextStore) Get(service, key string) (string, error) {
+	panic("Get called on a ContextSecretStore")
+}
+
+func (c contextStore) GetContext(ctx context.Context, service, key string) (string, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		return "", errors.New("missing deadline")
+	}
+	return c.mapStore.Get(service, key)
+}
+
+// TestSecretsConcurrent tests that secrets are looked up concurrently by a bounded number of workers
+func TestSecretsConcurrent(t *testing.T) {
+	type Conf struct {
+		Keys struct {
+			A, B, C, D, E, F, G, H, I, J, K, L string
+		}
+	}
+
+	store := &slowStore{mapStore: mapStore{"svc": {}}, delay: 20 * time.Millisecond}
+
+	var cfg Conf
+	c := &Env{Name: "svc", Type: JSON, ConfigStruct: &cfg, Store: store}
+	for _, key := range c.GetKeys() {
+		store.mapStore["svc"][key] = "value"
+	}
+	assert.Len(t, store.mapStore["svc"], 12)
+
+	err := GetContext(context.Background(), PROD, c)
+	assert.NoError(t, err)
+	assert.Equal(t, "value", cfg.Keys.L)
+	assert.Greater(t, store.max, 1)
+	assert.LessOrEqual(t, store.max, secretWorkers)
+}
+
+// TestGetContextTimeout tests that a hanging store is abandoned at the context deadline
+func TestGetContextTimeout(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Token  string `json:"token"`
+	}
+
+	hang := make(chan struct{})
+	defer close(hang)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var cfg Conf
+	start := time.Now()
+	err := GetContext(ctx, PROD, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        hangingStore{hang: hang},
+	})
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// every lookup that did not finish is reported against its field
+	var paths []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			paths = append(paths, fieldErr.Path)
+		}
+	}
+	assert.Equal(t, []string{"APIKey", "Token"}, paths)
+}
+
+// TestGetContextStore tests that a ContextSecretStore is given the load's context
+func TestGetContextStore(t *testing.T) {
+	type Conf struct {
+		APIKey string `json:"api_key"`
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var cfg Conf
+	err := GetContext(ctx, PROD, &Env{
+		Name:         "svc",
+		Type:         JSON,
+		ConfigStruct: &cfg,
+		Store:        contextStore{mapStore{"svc": {"api_key": "abc123"}}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+}
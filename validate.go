@@ -0,0 +1,158 @@
+package yae
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rule is a single rule of a validate tag, e.g. min=1.
+type rule struct {
+	name string
+	arg  string
+}
+
+/*
+parseRules splits a validate tag into its rules:
+
+	validate:"required,min=1,max=65535,oneof=debug info,regex=^[a-z]+$"
+
+oneof takes a space separated list of values. regex consumes the rest of the
+tag, so it must come last and may contain commas.
+*/
+func parseRules(tag string) []rule {
+	var rules []rule
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "regex=") {
+			item, tag = tag, ""
+		} else {
+			item, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(item), "=")
+		if name != "" {
+			rules = append(rules, rule{name: name, arg: arg})
+		}
+	}
+	return rules
+}
+
+/*
+validateValue checks v against the rules of a validate tag and returns the
+first rule that failed.
+
+A nil pointer is unset: it fails required and passes every other rule. A set
+pointer holds an explicit value, so it passes required even when it points to
+a zero value, and the value is checked by the other rules. Other values fail
+required when they are zero, and are always checked by the other rules, so a
+zero Port fails min=1. min and max compare numbers, including durations such
+as min=1s, and the length of strings, slices and maps.
+*/
+func validateValue(v reflect.Value, tag string) (string, error) {
+	pointer := v.IsValid() && v.Kind() == reflect.Pointer
+	for v.IsValid() && v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v = reflect.Value{}
+			break
+		}
+		v = v.Elem()
+	}
+	unset := !v.IsValid()
+
+	rules := parseRules(tag)
+	for _, r := range rules {
+		if r.name == "required" && (unset || !pointer && v.IsZero()) {
+			return r.name, fmt.Errorf("is required")
+		}
+	}
+	if unset {
+		return "", nil
+	}
+
+	for _, r := range rules {
+		var err error
+		switch r.name {
+		case "required":
+		case "min":
+			err = checkBound(v, r.arg, func(n, limit float64) bool { return n >= limit }, "at least")
+		case "max":
+			err = checkBound(v, r.arg, func(n, limit float64) bool { return n <= limit }, "at most")
+		case "oneof":
+			err = checkOneOf(v, r.arg)
+		case "regex":
+			err = checkRegex(v, r.arg)
+		default:
+			err = fmt.Errorf("unknown rule")
+		}
+		if err != nil {
+			return r.name, err
+		}
+	}
+	return "", nil
+}
+
+// checkBound compares the number or length of v to the limit in arg.
+func checkBound(v reflect.Value, arg string, ok func(n, limit float64) bool, bound string) error {
+	var (
+		n, limit float64
+		err      error
+		what     = "must be"
+	)
+
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(arg)
+		n, limit = float64(v.Int()), float64(d)
+	default:
+		limit, err = strconv.ParseFloat(arg, 64)
+
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			n, what = float64(v.Len()), "length must be"
+		default:
+			return fmt.Errorf("not supported for type %s", v.Type())
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("invalid limit %q: %w", arg, err)
+	}
+
+	if !ok(n, limit) {
+		return fmt.Errorf("%s %s %s", what, bound, arg)
+	}
+	return nil
+}
+
+// checkOneOf reports whether v is one of the space separated values in arg.
+func checkOneOf(v reflect.Value, arg string) error {
+	value := formatValue(v)
+	for _, allowed := range strings.Fields(arg) {
+		if value == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(strings.Fields(arg), ", "))
+}
+
+// checkRegex reports whether v matches the regular expression in arg.
+func checkRegex(v reflect.Value, arg string) error {
+	re, err := regexp.Compile(arg)
+	if err != nil {
+		return fmt.Errorf("invalid regex %q: %w", arg, err)
+	}
+	if !re.MatchString(formatValue(v)) {
+		return fmt.Errorf("must match %s", arg)
+	}
+	return nil
+}
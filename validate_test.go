@@ -0,0 +1,61 @@
+package yae
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRules(t *testing.T) {
+	assert.Equal(t, []rule{
+		{name: "required"},
+		{name: "min", arg: "1"},
+		{name: "oneof", arg: "debug info"},
+		{name: "regex", arg: "^[a-z]{1,3},?$"},
+	}, parseRules("required, min=1,oneof=debug info,regex=^[a-z]{1,3},?$"))
+}
+
+func TestValidateValue(t *testing.T) {
+	port, enabled := 0, false
+	tests := []struct {
+		value interface{}
+		tag   string
+		rule  string
+	}{
+		{"", "required", "required"},
+		{(*int)(nil), "required", "required"},
+		{&port, "required", ""},
+		{&port, "min=1", "min"},
+		{&enabled, "required", ""},
+		{(*int)(nil), "min=1", ""},
+		{0, "min=1,max=65535", "min"},
+		{"", "min=3", "min"},
+		{"", "oneof=debug info", "oneof"},
+		{8080, "required,min=1,max=65535", ""},
+		{70000, "min=1,max=65535", "max"},
+		{uint8(0), "min=1", "min"},
+		{1.5, "min=2.5", "min"},
+		{"ab", "min=3", "min"},
+		{[]string{"a", "b"}, "max=1", "max"},
+		{500 * time.Millisecond, "min=1s", "min"},
+		{2 * time.Second, "min=1s,max=1m", ""},
+		{"warn", "oneof=debug info", "oneof"},
+		{"info", "oneof=debug info", ""},
+		{"abc1", "regex=^[a-z]+$", "regex"},
+		{"abc", "regex=^[a-z]+$", ""},
+		{"abc", "regex=[", "regex"},
+		{"abc", "min=x", "min"},
+		{"abc", "unknown", "unknown"},
+	}
+
+	for _, tt := range tests {
+		v := reflect.New(reflect.TypeOf(tt.value)).Elem()
+		v.Set(reflect.ValueOf(tt.value))
+
+		rule, err := validateValue(v, tt.tag)
+		assert.Equal(t, tt.rule, rule, "%v %s", tt.value, tt.tag)
+		assert.Equal(t, tt.rule != "", err != nil, "%v %s: %v", tt.value, tt.tag, err)
+	}
+}
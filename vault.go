@@ -0,0 +1,347 @@
+package yae
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/johnmikee/yae/internal/sharedread"
+)
+
+// defaultVaultMount is the mount path of the KV v2 engine unless VaultStore.Mount is set.
+const defaultVaultMount = "secret"
+
+/*
+VaultStore is a SecretStore that reads and writes HashiCorp Vault KV v2
+secrets over the HTTP API.
+
+Each key is a field of a secret. The secret's path is the service, unless the
+key is written as path#field, which can be set per struct field with a secret
+tag:
+
+	type Config struct {
+		APIKey   string `json:"api_key"`                // field api_key of the service path
+		Password string `secret:"database/prod#password"` // field password of database/prod
+	}
+
+Concurrent lookups of the same path during a load share a single read.
+
+Requests are authenticated with Token, or with a token from an AppRole login
+with RoleID and SecretID, which is renewed by logging in again when Vault
+rejects it. Address, Token, RoleID, SecretID and Namespace default to
+VAULT_ADDR, VAULT_TOKEN, VAULT_ROLE_ID, VAULT_SECRET_ID and VAULT_NAMESPACE.
+
+A VaultStore is safe for concurrent use. It must not be copied after first use.
+*/
+type VaultStore struct {
+	Address      string       // Address of the Vault server, e.g. https://vault.example.com:8200
+	Token        string       // Token to authenticate with
+	RoleID       string       // AppRole role ID, used when Token is not set
+	SecretID     string       // AppRole secret ID
+	AppRoleMount string       // Mount path of the AppRole auth method, defaults to approle
+	Mount        string       // Mount path of the KV v2 engine, defaults to secret
+	Namespace    string       // Vault Enterprise namespace
+	Client       *http.Client // HTTP client, defaults to http.DefaultClient
+
+	mu    sync.Mutex
+	login string                        // token from the last AppRole login
+	reads sharedread.Group[vaultSecret] // reads in flight by path
+}
+
+// vaultSecret is the part of a KV v2 read response yae uses.
+type vaultSecret struct {
+	Data struct {
+		Data     map[string]interface{} `json:"data"`
+		Metadata struct {
+			Version int `json:"version"`
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+// vaultError is returned for a response with an unexpected status.
+type vaultError struct {
+	status int
+	errors []string
+}
+
+func (e *vaultError) Error() string {
+	if len(e.errors) == 0 {
+		return fmt.Sprintf("vault returned status %d", e.status)
+	}
+	return fmt.Sprintf("vault returned status %d: %s", e.status, strings.Join(e.errors, ", "))
+}
+
+// Get returns the field of the secret at the path of key or service.
+func (v *VaultStore) Get(service, key string) (string, error) {
+	return v.GetContext(context.Background(), service, key)
+}
+
+// GetContext returns the field of the secret at the path of key or service, giving up when ctx is done.
+func (v *VaultStore) GetContext(ctx context.Context, service, key string) (string, error) {
+	path, field := vaultPath(service, key)
+
+	// the fields of a struct usually share a path, so they share its read
+	secret, err := v.reads.Do(ctx, path, func() (vaultSecret, error) {
+		return v.read(ctx, path)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secret.Data.Data[field]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	// numbers, booleans and objects are returned as JSON
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode field %s of %s: %w", field, path, err)
+	}
+	return string(b), nil
+}
+
+// Set writes value to the field of the secret, keeping its other fields. The
+// write is checked against the version that was read, so concurrent writers
+// cannot silently overwrite each other.
+func (v *VaultStore) Set(service, key, value string) error {
+	ctx := context.Background()
+	path, field := vaultPath(service, key)
+
+	secret, err := v.read(ctx, path)
+	if err != nil && !errors.Is(err, ErrSecretNotFound) {
+		return err
+	}
+
+	data := secret.Data.Data
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data[field] = value
+
+	return v.write(ctx, path, data, secret.Data.Metadata.Version)
+}
+
+// Delete removes the field from the secret. The latest version of the secret
+// is deleted once it has no fields left.
+func (v *VaultStore) Delete(service, key string) error {
+	ctx := context.Background()
+	path, field := vaultPath(service, key)
+
+	secret, err := v.read(ctx, path)
+	if err != nil {
+		return err
+	}
+	if _, ok := secret.Data.Data[field]; !ok {
+		return ErrSecretNotFound
+	}
+
+	delete(secret.Data.Data, field)
+	if len(secret.Data.Data) > 0 {
+		return v.write(ctx, path, secret.Data.Data, secret.Data.Metadata.Version)
+	}
+	return v.do(ctx, http.MethodDelete, v.dataPath(path), nil, nil)
+}
+
+// List returns the sorted fields of the secret at the service path.
+func (v *VaultStore) List(service string) ([]string, error) {
+	secret, err := v.read(context.Background(), service)
+	if errors.Is(err, ErrSecretNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(secret.Data.Data))
+	for k := range secret.Data.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// vaultPath splits a path#field key, or returns the service as the path of the field key.
+func vaultPath(service, key string) (string, string) {
+	if path, field, ok := strings.Cut(key, "#"); ok {
+		return strings.Trim(path, "/"), field
+	}
+	return strings.Trim(service, "/"), key
+}
+
+// read returns the latest version of the secret at path.
+func (v *VaultStore) read(ctx context.Context, path string) (vaultSecret, error) {
+	var secret vaultSecret
+	err := v.do(ctx, http.MethodGet, v.dataPath(path), nil, &secret)
+	return secret, err
+}
+
+// write replaces the secret at path with data if its current version is still version.
+func (v *VaultStore) write(ctx context.Context, path string, data map[string]interface{}, version int) error {
+	body := map[string]interface{}{
+		"data":    data,
+		"options": map[string]interface{}{"cas": version},
+	}
+	return v.do(ctx, http.MethodPost, v.dataPath(path), body, nil)
+}
+
+func (v *VaultStore) dataPath(path string) string {
+	mount := v.Mount
+	if mount == "" {
+		mount = defaultVaultMount
+	}
+	return fmt.Sprintf("/v1/%s/data/%s", strings.Trim(mount, "/"), path)
+}
+
+// do sends an authenticated request and decodes the response into out. A 404
+// is returned as ErrSecretNotFound. When an AppRole token is rejected, do logs
+// in again and retries once.
+func (v *VaultStore) do(ctx context.Context, method, path string, body, out interface{}) error {
+	token, err := v.token(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	err = v.request(ctx, method, path, token, body, out)
+
+	var verr *vaultError
+	if errors.As(err, &verr) && verr.status == http.StatusForbidden && v.usesAppRole() {
+		if token, err = v.token(ctx, true); err != nil {
+			return err
+		}
+		err = v.request(ctx, method, path, token, body, out)
+	}
+	return err
+}
+
+// request sends a single request to Vault.
+func (v *VaultStore) request(ctx context.Context, method, path, token string, body, out interface{}) error {
+	addr := v.Address
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if addr == "" {
+		return errors.New("vault address is not set, set Address or VAULT_ADDR")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode vault request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(addr, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create vault request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if ns := envDefault(v.Namespace, "VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrSecretNotFound
+	case resp.StatusCode >= 300:
+		verr := &vaultError{status: resp.StatusCode}
+		var payload struct {
+			Errors []string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&payload) == nil {
+			verr.errors = payload.Errors
+		}
+		return verr
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return nil
+}
+
+func (v *VaultStore) usesAppRole() bool {
+	return envDefault(v.Token, "VAULT_TOKEN") == "" && envDefault(v.RoleID, "VAULT_ROLE_ID") != ""
+}
+
+// token returns the token to authenticate with, logging in with AppRole when
+// there is no token yet or renew is set.
+func (v *VaultStore) token(ctx context.Context, renew bool) (string, error) {
+	if token := envDefault(v.Token, "VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+	if !v.usesAppRole() {
+		return "", errors.New("vault is not authenticated, set Token or RoleID and SecretID")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.login != "" && !renew {
+		return v.login, nil
+	}
+
+	mount := v.AppRoleMount
+	if mount == "" {
+		mount = "approle"
+	}
+	body := map[string]string{
+		"role_id":   envDefault(v.RoleID, "VAULT_ROLE_ID"),
+		"secret_id": envDefault(v.SecretID, "VAULT_SECRET_ID"),
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	path := fmt.Sprintf("/v1/auth/%s/login", strings.Trim(mount, "/"))
+	if err := v.request(ctx, http.MethodPost, path, "", body, &resp); err != nil {
+		return "", fmt.Errorf("failed to log in to vault with approle: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", errors.New("failed to log in to vault with approle: no token returned")
+	}
+
+	v.login = resp.Auth.ClientToken
+	return v.login, nil
+}
+
+// envDefault returns value, or the environment variable env when value is empty.
+func envDefault(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
@@ -0,0 +1,195 @@
+package yae_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/johnmikee/yae"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVault is a minimal stand-in for the Vault KV v2 and AppRole APIs.
+type fakeVault struct {
+	mu       sync.Mutex
+	secrets  map[string]map[string]interface{}
+	versions map[string]int
+	tokens   map[string]bool
+	logins   int
+	reads    int
+	delay    time.Duration
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	v := &fakeVault{
+		secrets:  map[string]map[string]interface{}{},
+		versions: map[string]int{},
+		tokens:   map[string]bool{"root": true},
+	}
+	srv := httptest.NewServer(v)
+	t.Cleanup(srv.Close)
+	return v, srv
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		time.Sleep(v.delay)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r.URL.Path == "/v1/auth/approle/login" {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": {"invalid role or secret ID"}})
+			return
+		}
+		v.logins++
+		v.tokens["approle-token"] = true
+		json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]string{"client_token": "approle-token"}})
+		return
+	}
+
+	if !v.tokens[r.Header.Get("X-Vault-Token")] {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")
+	switch r.Method {
+	case http.MethodGet:
+		v.reads++
+		data, ok := v.secrets[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": {}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+			"data":     data,
+			"metadata": map[string]int{"version": v.versions[path]},
+		}})
+	case http.MethodPost:
+		var body struct {
+			Data    map[string]interface{} `json:"data"`
+			Options struct {
+				CAS int `json:"cas"`
+			} `json:"options"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Options.CAS != v.versions[path] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": {"check-and-set parameter did not match the current version"}})
+			return
+		}
+		v.secrets[path] = body.Data
+		v.versions[path]++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("{}"))
+	case http.MethodDelete:
+		delete(v.secrets, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestVaultStore(t *testing.T) {
+	_, srv := newFakeVault(t)
+	store := &yae.VaultStore{Address: srv.URL, Token: "root"}
+
+	_, err := store.Get("myapp", "api_key")
+	assert.ErrorIs(t, err, yae.ErrSecretNotFound)
+
+	assert.NoError(t, store.Set("myapp", "api_key", "abc123"))
+	assert.NoError(t, store.Set("myapp", "region", "eu-west-1"))
+	assert.NoError(t, store.Set("myapp", "database/prod#password", "hunter2"))
+
+	value, err := store.Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+
+	value, err = store.Get("other", "database/prod#password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	keys, err := store.List("myapp")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "region"}, keys)
+
+	assert.NoError(t, store.Delete("myapp", "region"))
+	assert.ErrorIs(t, store.Delete("myapp", "region"), yae.ErrSecretNotFound)
+	assert.NoError(t, store.Delete("myapp", "api_key"))
+	_, err = store.Get("myapp", "api_key")
+	assert.ErrorIs(t, err, yae.ErrSecretNotFound)
+
+	_, err = (&yae.VaultStore{Address: srv.URL, Token: "bad"}).Get("myapp", "api_key")
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestVaultStoreAppRole(t *testing.T) {
+	vault, srv := newFakeVault(t)
+	vault.secrets["myapp"] = map[string]interface{}{"api_key": "abc123", "port": 8080}
+	vault.secrets["database/prod"] = map[string]interface{}{"password": "hunter2"}
+
+	type Conf struct {
+		APIKey   string `json:"api_key"`
+		Port     int    `json:"port"`
+		Password string `json:"password" secret:"database/prod#password"`
+	}
+
+	store := &yae.VaultStore{Address: srv.URL, RoleID: "role", SecretID: "secret"}
+
+	var cfg Conf
+	loader := yae.New(
+		yae.WithFile("myapp.json", yae.JSON),
+		yae.WithKeyringService("myapp"),
+		yae.WithSecretStore(store),
+	)
+	err := loader.Load(yae.PROD, &cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, Conf{APIKey: "abc123", Port: 8080, Password: "hunter2"}, cfg)
+	assert.Equal(t, 1, vault.logins)
+
+	// a revoked token is renewed by logging in again
+	vault.mu.Lock()
+	delete(vault.tokens, "approle-token")
+	vault.mu.Unlock()
+
+	value, err := store.Get("myapp", "api_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", value)
+	assert.Equal(t, 2, vault.logins)
+
+	_, err = (&yae.VaultStore{Address: srv.URL, RoleID: "role", SecretID: "wrong"}).Get("myapp", "api_key")
+	assert.ErrorContains(t, err, "invalid role or secret ID")
+}
+
+// TestVaultStoreSharedReads tests that the fields of a struct under one path are read once per load
+func TestVaultStoreSharedReads(t *testing.T) {
+	vault, srv := newFakeVault(t)
+	vault.secrets["myapp"] = map[string]interface{}{"api_key": "abc123", "region": "eu-west-1", "token": "t0k3n"}
+	vault.delay = 50 * time.Millisecond
+
+	type Conf struct {
+		APIKey string `json:"api_key"`
+		Region string `json:"region"`
+		Token  string `json:"token"`
+	}
+
+	var cfg Conf
+	err := yae.New(
+		yae.WithFile("myapp.json", yae.JSON),
+		yae.WithKeyringService("myapp"),
+		yae.WithSecretStore(&yae.VaultStore{Address: srv.URL, Token: "root"}),
+	).Load(yae.PROD, &cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, Conf{APIKey: "abc123", Region: "eu-west-1", Token: "t0k3n"}, cfg)
+	assert.Equal(t, 1, vault.reads)
+}
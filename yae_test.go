@@ -1,8 +1,13 @@
 package yae_test
 
 import (
+	"bytes"
+	"errors"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,13 +18,13 @@ import (
 )
 
 type AppConfig struct {
-	APIKey      string `json:"api_key" yaml:"api_key"`
-	DatabaseURL string `json:"database_url" yaml:"database_url"`
+	APIKey      string `json:"api_key" yaml:"api_key" toml:"api_key"`
+	DatabaseURL string `json:"database_url" yaml:"database_url" toml:"database_url"`
 }
 
 type ConfigStruct struct {
-	Field1 string `json:"field1" yaml:"field1"`
-	Field2 int    `json:"field2" yaml:"field2"`
+	Field1 string `json:"field1" yaml:"field1" toml:"field1"`
+	Field2 int    `json:"field2" yaml:"field2" toml:"field2"`
 }
 
 var (
@@ -30,6 +35,7 @@ var (
 	}`)
 
 	testYamlfile = ".testconfig.yaml"
+	testTomlfile = ".testconfig.toml"
 )
 
 func TestEnvNoPrefix(t *testing.T) {
@@ -83,6 +89,304 @@ func TestEnvWithPrefix(t *testing.T) {
 	assert.NotEqual(t, "localhost:5432", appConfig.DatabaseURL)
 }
 
+func TestEnvNested(t *testing.T) {
+	type Conf struct {
+		APIKey   string `json:"api_key"`
+		Database struct {
+			Host string
+			Port int `json:"port"`
+		}
+	}
+
+	os.Setenv("YAE_API_KEY", "abc123")
+	os.Setenv("YAE_DATABASE_HOST", "db.internal")
+	os.Setenv("YAE_DATABASE_PORT", "5432")
+	defer os.Unsetenv("YAE_API_KEY")
+	defer os.Unsetenv("YAE_DATABASE_HOST")
+	defer os.Unsetenv("YAE_DATABASE_PORT")
+
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 5432, cfg.Database.Port)
+}
+
+func TestEnvSliceAndMap(t *testing.T) {
+	type Conf struct {
+		AllowedHosts []string          `json:"allowed_hosts"`
+		Ports        []int             `json:"ports" sep:" "`
+		Labels       map[string]string `json:"labels"`
+	}
+
+	os.Setenv("ALLOWED_HOSTS", "a,b,c")
+	os.Setenv("PORTS", "80 443")
+	os.Setenv("LABELS", "team=x,tier=y")
+	defer os.Unsetenv("ALLOWED_HOSTS")
+	defer os.Unsetenv("PORTS")
+	defer os.Unsetenv("LABELS")
+
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, cfg.AllowedHosts)
+	assert.Equal(t, []int{80, 443}, cfg.Ports)
+	assert.Equal(t, map[string]string{"team": "x", "tier": "y"}, cfg.Labels)
+}
+
+func TestEnvPointers(t *testing.T) {
+	type Conf struct {
+		EnableX *bool `json:"enable_x"`
+		Retries *int  `json:"retries"`
+	}
+
+	os.Setenv("ENABLE_X", "false")
+	os.Setenv("RETRIES", "0")
+	defer os.Unsetenv("ENABLE_X")
+	defer os.Unsetenv("RETRIES")
+
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.NoError(t, err)
+	if assert.NotNil(t, cfg.EnableX) {
+		assert.False(t, *cfg.EnableX)
+	}
+	if assert.NotNil(t, cfg.Retries) {
+		assert.Equal(t, 0, *cfg.Retries)
+	}
+}
+
+func TestEnvDefaults(t *testing.T) {
+	type Conf struct {
+		Host    string        `json:"host" default:"localhost"`
+		Port    int           `json:"port" default:"8080"`
+		Timeout time.Duration `json:"timeout" default:"30s"`
+		Debug   bool          `json:"debug" default:""`
+	}
+
+	os.Setenv("PORT", "9090")
+	defer os.Unsetenv("PORT")
+
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", cfg.Host)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.Equal(t, 30*time.Second, cfg.Timeout)
+	assert.False(t, cfg.Debug)
+}
+
+func TestFileDefaults(t *testing.T) {
+	type Conf struct {
+		Host string `json:"host" default:"localhost"`
+		Port int    `json:"port" default:"8080"`
+	}
+
+	err := os.WriteFile(testJsonfile, []byte(`{"port": 9090}`), 0o644)
+	if err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	defer os.Remove(testJsonfile)
+
+	var cfg Conf
+	err = yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", cfg.Host)
+	assert.Equal(t, 9090, cfg.Port)
+}
+
+func TestInvalidDefault(t *testing.T) {
+	type Conf struct {
+		Port int `json:"port" default:"eighty"`
+	}
+
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.Error(t, err)
+}
+
+func TestEnvOptional(t *testing.T) {
+	type Conf struct {
+		APIKey  string `json:"api_key"`
+		Region  string `json:"region" yae:"optional"`
+		Workers int    `json:"workers" yae:"optional"`
+	}
+
+	os.Setenv("API_KEY", "abc123")
+	defer os.Unsetenv("API_KEY")
+
+	cfg := Conf{Workers: 4}
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", cfg.APIKey)
+	// missing optional fields are left untouched
+	assert.Equal(t, "", cfg.Region)
+	assert.Equal(t, 4, cfg.Workers)
+}
+
+func TestEnvRequiredMissing(t *testing.T) {
+	type Conf struct {
+		APIKey   string `json:"api_key" yae:"required"`
+		Region   string `json:"region" yae:"optional"`
+		Database struct {
+			Host string `json:"host"`
+		} `json:"database"`
+	}
+
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &cfg,
+		},
+	)
+
+	var missing *yae.MissingEnvError
+	if assert.ErrorAs(t, err, &missing) {
+		assert.Equal(t, []string{"YAE_API_KEY", "YAE_DATABASE_HOST"}, missing.Names)
+		assert.Equal(t, []string{"APIKey", "Database.Host"}, missing.Fields)
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	type Conf struct {
+		APIKey  string `json:"api_key"`
+		Port    int    `json:"port"`
+		Workers int    `json:"workers" default:"many"`
+		Debug   bool   `json:"debug"`
+	}
+
+	t.Setenv("YAE_PORT", "eighty")
+	t.Setenv("YAE_DEBUG", "maybe")
+
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &cfg,
+		},
+	)
+
+	// every error is reported, not only the first one
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !assert.True(t, ok) {
+		return
+	}
+	errs := joined.Unwrap()
+	assert.Len(t, errs, 4)
+
+	var fieldErr *yae.FieldError
+	if assert.ErrorAs(t, errs[0], &fieldErr) {
+		assert.Equal(t, "Workers", fieldErr.Path)
+		assert.Equal(t, yae.SourceDefault, fieldErr.Source)
+	}
+	if assert.ErrorAs(t, errs[1], &fieldErr) {
+		assert.Equal(t, "Port", fieldErr.Path)
+		assert.Equal(t, yae.SourceEnv, fieldErr.Source)
+	}
+
+	var numErr *strconv.NumError
+	assert.ErrorAs(t, err, &numErr)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+
+	// fields that failed are not reported as missing as well
+	var missing *yae.MissingEnvError
+	if assert.ErrorAs(t, err, &missing) {
+		assert.Equal(t, []string{"YAE_API_KEY"}, missing.Names)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	type Conf struct {
+		Host     string `json:"host" yae:"public"`
+		Password string `json:"password"`
+	}
+
+	t.Setenv("YAE_HOST", "db.internal")
+	t.Setenv("YAE_PASSWORD", "hunter2")
+
+	defaultLogger := slog.Default()
+
+	var buf bytes.Buffer
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &cfg,
+			Logger:       slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", cfg.Password)
+
+	assert.Same(t, defaultLogger, slog.Default())
+	// values are never logged, not even for public fields
+	assert.Contains(t, buf.String(), "field=Host source=env from=YAE_HOST\n")
+	assert.Contains(t, buf.String(), "field=Password source=env from=YAE_PASSWORD\n")
+	assert.NotContains(t, buf.String(), "db.internal")
+	assert.NotContains(t, buf.String(), "hunter2")
+}
+
 func TestJSON(t *testing.T) {
 	err := os.WriteFile(testJsonfile, fileContent, 0o644)
 	if err != nil {
@@ -135,6 +439,358 @@ func TestYAML(t *testing.T) {
 	assert.Equal(t, "https://example.com/db", appConfig.DatabaseURL)
 }
 
+func TestTOML(t *testing.T) {
+	tomlData := []byte(`database_url = "https://example.com/db"
+api_key = "secret-api-key"
+`)
+
+	err := os.WriteFile(testTomlfile, tomlData, 0o644)
+	if err != nil {
+		t.Fatalf("failed to create TOML file: %v", err)
+	}
+	defer os.Remove(testTomlfile)
+
+	var appConfig AppConfig
+	err = yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testTomlfile,
+			Type:         yae.TOML,
+			ConfigStruct: &appConfig,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret-api-key", appConfig.APIKey)
+	assert.Equal(t, "https://example.com/db", appConfig.DatabaseURL)
+}
+
+func TestTOMLEnv(t *testing.T) {
+	type Conf struct {
+		Token string `yaml:"token" toml:"token_value"`
+	}
+
+	os.Setenv("YAE_TOKEN_VALUE", "from-toml-tag")
+	os.Setenv("YAE_TOKEN", "from-yaml-tag")
+	defer os.Unsetenv("YAE_TOKEN_VALUE")
+	defer os.Unsetenv("YAE_TOKEN")
+
+	var cfg Conf
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testTomlfile,
+			Type:         yae.TOML,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.NoError(t, err)
+	// the toml tag takes precedence over the yaml tag
+	assert.Equal(t, "from-toml-tag", cfg.Token)
+}
+
+func TestLayeredFileAndEnv(t *testing.T) {
+	err := os.WriteFile(testJsonfile, fileContent, 0o644)
+	if err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	defer os.Remove(testJsonfile)
+
+	os.Setenv("YAE_DATABASE_URL", "localhost:9999")
+	defer os.Unsetenv("YAE_DATABASE_URL")
+
+	var appConfig AppConfig
+	err = yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &appConfig,
+		},
+	)
+	assert.NoError(t, err)
+	// the file provides the api key, a single env var overrides the database url
+	assert.Equal(t, "secret-api-key", appConfig.APIKey)
+	assert.Equal(t, "localhost:9999", appConfig.DatabaseURL)
+}
+
+func TestLayeredFileMissingRequired(t *testing.T) {
+	err := os.WriteFile(testJsonfile, []byte(`{"api_key": "secret-api-key"}`), 0o644)
+	if err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	defer os.Remove(testJsonfile)
+
+	// fields the file leaves out are required like without a file
+	var appConfig AppConfig
+	err = yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &appConfig,
+		},
+	)
+
+	var missing *yae.MissingEnvError
+	if assert.ErrorAs(t, err, &missing) {
+		assert.Equal(t, []string{"DATABASE_URL"}, missing.Names)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	os.Setenv("API_KEY", "abc123")
+	os.Setenv("DATABASE_URL", "localhost:5432")
+	defer os.Unsetenv("API_KEY")
+	defer os.Unsetenv("DATABASE_URL")
+
+	var appConfig AppConfig
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &appConfig,
+			Overrides: map[string]string{
+				"api_key":     "from-key",
+				"DatabaseURL": "from-path",
+			},
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-key", appConfig.APIKey)
+	assert.Equal(t, "from-path", appConfig.DatabaseURL)
+
+	err = yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			ConfigStruct: &appConfig,
+			Overrides:    map[string]string{"nope": "value"},
+		},
+	)
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	err := os.WriteFile(testJsonfile, fileContent, 0o644)
+	if err != nil {
+		t.Fatalf("failed to create config file: %v", err)
+	}
+	defer os.Remove(testJsonfile)
+
+	t.Setenv("YAE_API_KEY", "from-env")
+
+	cfg, err := yae.Load[AppConfig](
+		yae.PROD,
+		yae.WithFile(testJsonfile, yae.JSON),
+		yae.WithEnvPrefix("YAE"),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", cfg.APIKey)
+	assert.Equal(t, "https://example.com/db", cfg.DatabaseURL)
+}
+
+func TestLoadNotStruct(t *testing.T) {
+	_, err := yae.Load[map[string]string](yae.PROD, yae.WithFile(testJsonfile, yae.JSON))
+	assert.ErrorIs(t, err, yae.ErrInvalidConfigStruct)
+}
+
+func TestInvalidConfigStruct(t *testing.T) {
+	var appConfig AppConfig
+	for _, cs := range []interface{}{nil, appConfig, &appConfig.APIKey, (*AppConfig)(nil)} {
+		err := yae.Get(
+			yae.PROD,
+			&yae.Env{
+				Name:         testJsonfile,
+				Type:         yae.JSON,
+				ConfigStruct: cs,
+			},
+		)
+		assert.ErrorIs(t, err, yae.ErrInvalidConfigStruct)
+	}
+}
+
+type validatedConfig struct {
+	Port     int    `json:"port" validate:"min=1,max=65535"`
+	LogLevel string `json:"log_level" validate:"oneof=debug info"`
+	Endpoint string `json:"endpoint" yae:"optional" validate:"required"`
+	Name     string `json:"name" validate:"regex=^[a-z]+(-[a-z]+)*$"`
+}
+
+func (c *validatedConfig) Validate() error {
+	if c.LogLevel == "debug" && c.Port == 80 {
+		return errors.New("debug logging is not allowed on port 80")
+	}
+	return nil
+}
+
+func TestValidation(t *testing.T) {
+	t.Setenv("YAE_PORT", "70000")
+	t.Setenv("YAE_LOG_LEVEL", "warn")
+	t.Setenv("YAE_NAME", "my-service")
+
+	var cfg validatedConfig
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &cfg,
+		},
+	)
+
+	var paths []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var validationErr *yae.ValidationError
+		if assert.ErrorAs(t, err, &validationErr) {
+			paths = append(paths, validationErr.Path+" "+validationErr.Rule)
+		}
+	}
+	assert.Equal(t, []string{"Port max", "LogLevel oneof", "Endpoint required"}, paths)
+}
+
+func TestValidateHook(t *testing.T) {
+	t.Setenv("YAE_PORT", "80")
+	t.Setenv("YAE_LOG_LEVEL", "debug")
+	t.Setenv("YAE_ENDPOINT", "https://example.com")
+	t.Setenv("YAE_NAME", "my-service")
+
+	var cfg validatedConfig
+	env := &yae.Env{
+		Name:         testJsonfile,
+		Type:         yae.JSON,
+		EnvPrefix:    "YAE",
+		ConfigStruct: &cfg,
+	}
+	err := yae.Get(yae.PROD, env)
+	assert.EqualError(t, err, "Validate hook failed: debug logging is not allowed on port 80")
+
+	t.Setenv("YAE_LOG_LEVEL", "info")
+	assert.NoError(t, yae.Get(yae.PROD, env))
+}
+
+func TestValidationPointers(t *testing.T) {
+	type pointerConfig struct {
+		EnableX *bool `json:"enable_x" validate:"required"`
+		Retries *int  `json:"retries" yae:"optional" validate:"min=1"`
+	}
+
+	t.Setenv("YAE_ENABLE_X", "false")
+	t.Setenv("YAE_RETRIES", "0")
+
+	var cfg pointerConfig
+	env := &yae.Env{
+		Name:         testJsonfile,
+		Type:         yae.JSON,
+		EnvPrefix:    "YAE",
+		ConfigStruct: &cfg,
+	}
+	err := yae.Get(yae.PROD, env)
+
+	// an explicit false is set, an explicit 0 is checked
+	var validationErr *yae.ValidationError
+	if assert.ErrorAs(t, err, &validationErr) {
+		assert.Equal(t, "Retries", validationErr.Path)
+		assert.Equal(t, "min", validationErr.Rule)
+	}
+	if assert.NotNil(t, cfg.EnableX) {
+		assert.False(t, *cfg.EnableX)
+	}
+
+	t.Setenv("YAE_RETRIES", "3")
+	cfg = pointerConfig{}
+	assert.NoError(t, yae.Get(yae.PROD, env))
+}
+
+type hookedConfig struct {
+	Host     string `json:"host" yae:"public"`
+	Port     int    `json:"port"`
+	LogLevel string `json:"log_level" default:"INFO" validate:"oneof=debug info"`
+
+	calls []string
+	meta  yae.LoadMeta
+	fail  string
+}
+
+func (c *hookedConfig) hook(name string) error {
+	c.calls = append(c.calls, name)
+	if c.fail == name {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (c *hookedConfig) SetDefaults() error {
+	c.Host, c.Port = "localhost", 8080
+	return c.hook(yae.HookSetDefaults)
+}
+
+func (c *hookedConfig) Normalize() error {
+	c.LogLevel = strings.ToLower(c.LogLevel)
+	return c.hook(yae.HookNormalize)
+}
+
+func (c *hookedConfig) Validate() error {
+	return c.hook(yae.HookValidate)
+}
+
+func (c *hookedConfig) AfterLoad(meta yae.LoadMeta) error {
+	c.meta = meta
+	return c.hook(yae.HookAfterLoad)
+}
+
+func TestHooks(t *testing.T) {
+	t.Setenv("YAE_PORT", "9090")
+
+	var cfg hookedConfig
+	err := yae.Get(
+		yae.PROD,
+		&yae.Env{
+			Name:         testJsonfile,
+			Type:         yae.JSON,
+			EnvPrefix:    "YAE",
+			ConfigStruct: &cfg,
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{yae.HookSetDefaults, yae.HookNormalize, yae.HookValidate, yae.HookAfterLoad}, cfg.calls)
+
+	// SetDefaults runs before the sources, Normalize before validation
+	assert.Equal(t, "localhost", cfg.Host)
+	assert.Equal(t, 9090, cfg.Port)
+	assert.Equal(t, "info", cfg.LogLevel)
+
+	assert.Equal(t, yae.PROD, cfg.meta.EnvType)
+	assert.Equal(t, "", cfg.meta.File)
+	assert.Equal(t, yae.Origin{Source: yae.SourceDefault, Name: yae.HookSetDefaults, Value: "localhost"}, cfg.meta.Provenance["Host"])
+	assert.Equal(t, yae.SourceEnv, cfg.meta.Provenance["Port"].Source)
+}
+
+func TestHookError(t *testing.T) {
+	for _, hook := range []string{yae.HookSetDefaults, yae.HookNormalize, yae.HookValidate, yae.HookAfterLoad} {
+		cfg := hookedConfig{fail: hook}
+		err := yae.Get(
+			yae.PROD,
+			&yae.Env{
+				Name:         testJsonfile,
+				Type:         yae.JSON,
+				EnvPrefix:    "YAE",
+				ConfigStruct: &cfg,
+			},
+		)
+
+		var hookErr *yae.HookError
+		if assert.ErrorAs(t, err, &hookErr, hook) {
+			assert.Equal(t, hook, hookErr.Hook)
+			assert.EqualError(t, hookErr.Err, "boom")
+		}
+	}
+}
+
 func TestInvalidFile(t *testing.T) {
 	invalidData := []byte(`{json "invalid": "json"}`)
 	err := os.WriteFile(testJsonfile, invalidData, 0o644)
@@ -405,4 +1061,33 @@ field2: 42`
 			t.Errorf("expected field1=value1 and field2=42, got field1=%s and field2=%d", config.Field1, config.Field2)
 		}
 	})
+
+	t.Run("TomlFile", func(t *testing.T) {
+		fileContent := `field1 = "value1"
+field2 = 42`
+		fileName := "config.toml"
+		filePath := filepath.Join(os.TempDir(), fileName)
+		if err := os.WriteFile(filePath, []byte(fileContent), 0o644); err != nil {
+			t.Fatalf("failed to write test config file: %v", err)
+		}
+		defer os.Remove(filePath)
+
+		env := &yae.Env{
+			Name:         fileName,
+			Path:         os.TempDir(),
+			Type:         yae.TOML,
+			ConfigStruct: &ConfigStruct{},
+			Debug:        true,
+		}
+
+		err := yae.LoadConfig(env)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		config := env.ConfigStruct.(*ConfigStruct)
+		if config.Field1 != "value1" || config.Field2 != 42 {
+			t.Errorf("expected field1=value1 and field2=42, got field1=%s and field2=%d", config.Field1, config.Field2)
+		}
+	})
 }